@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	src, err := generate(data{
+		Package: "github.com/eleanorhealth/milo/examples/store/storage",
+		Var:     "MiloEntityModelMap",
+		URLEnv:  urlEnv,
+	})
+	assert.NoError(err)
+
+	assert.Contains(string(src), `models "github.com/eleanorhealth/milo/examples/store/storage"`)
+	assert.Contains(string(src), `pg.ParseURL(os.Getenv("SCHEMACHECK_DATABASE_URL"))`)
+	assert.Contains(string(src), "milo.DetectSchemaDrift(context.Background(), db, models.MiloEntityModelMap)")
+}
+
+func TestResolveImportPath(t *testing.T) {
+	assert := assert.New(t)
+
+	importPath, err := resolveImportPath("../../examples/store/storage")
+	assert.NoError(err)
+	assert.Equal("github.com/eleanorhealth/milo/examples/store/storage", importPath)
+
+	importPath, err = resolveImportPath("github.com/acme/app/storage")
+	assert.NoError(err)
+	assert.Equal("github.com/acme/app/storage", importPath)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type columnsData struct {
+	Package string
+	Models  []modelColumns
+}
+
+type modelColumns struct {
+	TypeName string
+	VarName  string
+	Columns  []column
+}
+
+type column struct {
+	Field string
+	Name  string
+}
+
+// parseModelColumns parses the Go files in dir and returns the columns of every struct that has at least one field
+// with a pg tag. If models isn't empty, only those structs are returned. Column names follow go-pg's rules: the name
+// in the pg tag or, if it's empty, the field name in snake case.
+func parseModelColumns(dir string, models []string) (*columnsData, error) {
+	fset := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	structs := make(map[string]*ast.StructType)
+
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			typeSpec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+
+			if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+				structs[typeSpec.Name.Name] = structType
+			}
+
+			return false
+		})
+	}
+
+	names := models
+	if len(names) == 0 {
+		for name, structType := range structs {
+			if hasPGTag(structType) {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+	}
+
+	data := &columnsData{
+		Package: pkg.Name,
+	}
+
+	for _, name := range names {
+		structType, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("unable to find struct %s in %s", name, dir)
+		}
+
+		columns, err := structColumns(structs, structType, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		data.Models = append(data.Models, modelColumns{
+			TypeName: name,
+			VarName:  exported(name) + "Columns",
+			Columns:  columns,
+		})
+	}
+
+	return data, nil
+}
+
+func structColumns(structs map[string]*ast.StructType, structType *ast.StructType, seen map[string]bool) ([]column, error) {
+	var columns []column
+
+	for _, field := range structType.Fields.List {
+		tag := pgTag(field)
+
+		if tag == "-" {
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+		if hasOption(options, "rel:") {
+			continue
+		}
+
+		// Embedded structs from the same package are inlined like go-pg does.
+		if len(field.Names) == 0 {
+			ident, ok := embeddedIdent(field.Type)
+			if !ok || seen[ident.Name] {
+				continue
+			}
+
+			embedded, ok := structs[ident.Name]
+			if !ok {
+				continue
+			}
+
+			if seen == nil {
+				seen = make(map[string]bool)
+			}
+			seen[ident.Name] = true
+
+			embeddedColumns, err := structColumns(structs, embedded, seen)
+			if err != nil {
+				return nil, err
+			}
+
+			columns = append(columns, embeddedColumns...)
+
+			continue
+		}
+
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+
+			// The first option is the column name unless it's a key:value option.
+			columnName := options[0]
+			if strings.Contains(columnName, ":") {
+				columnName = ""
+			}
+
+			if len(columnName) == 0 {
+				columnName = underscore(name.Name)
+			}
+
+			columns = append(columns, column{
+				Field: name.Name,
+				Name:  columnName,
+			})
+		}
+	}
+
+	return columns, nil
+}
+
+func hasPGTag(structType *ast.StructType) bool {
+	for _, field := range structType.Fields.List {
+		if len(pgTag(field)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func pgTag(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+
+	return reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("pg")
+}
+
+func hasOption(options []string, prefix string) bool {
+	for _, option := range options {
+		if strings.HasPrefix(option, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func embeddedIdent(expr ast.Expr) (*ast.Ident, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	ident, ok := expr.(*ast.Ident)
+
+	return ident, ok
+}
+
+func exported(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// underscore converts "CamelCasedString" to "camel_cased_string" the same way go-pg does.
+func underscore(s string) string {
+	r := make([]byte, 0, len(s)+5)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+
+			if i > 0 && i+1 < len(s) && (isLower(s[i-1]) || isLower(s[i+1])) {
+				r = append(r, '_', c)
+			} else {
+				r = append(r, c)
+			}
+		} else {
+			r = append(r, s[i])
+		}
+	}
+
+	return string(r)
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
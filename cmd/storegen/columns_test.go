@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const columnsTestSrc = `package storage
+
+type customer struct {
+	tableName struct{} ` + "`pg:\"customers\"`" + `
+
+	ID string ` + "`pg:\"id\"`" + `
+
+	NameFirst string ` + "`pg:\"name_first\"`" + `
+	NameLast  string ` + "`pg:\",use_zero\"`" + `
+	Ignored   string ` + "`pg:\"-\"`" + `
+	internal  string
+
+	Addresses []*address ` + "`pg:\"rel:has-many\"`" + `
+	Profile   *profile   ` + "`pg:\"profile,rel:has-one\"`" + `
+
+	timestamps
+}
+
+type timestamps struct {
+	CreatedAt string ` + "`pg:\"created_at\"`" + `
+	UpdatedAt string
+}
+
+type address struct {
+	ID         string ` + "`pg:\"id\"`" + `
+	CustomerID string ` + "`pg:\"customer_id\"`" + `
+}
+
+type profile struct {
+	ID string ` + "`pg:\"id\"`" + `
+}
+
+type notAModel struct {
+	Foo string
+}
+`
+
+func TestParseModelColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "storage.go"), []byte(columnsTestSrc), 0644)
+	assert.NoError(err)
+
+	d, err := parseModelColumns(dir, nil)
+	assert.NoError(err)
+	assert.Equal("storage", d.Package)
+	assert.Equal([]modelColumns{
+		{
+			TypeName: "address",
+			VarName:  "AddressColumns",
+			Columns: []column{
+				{Field: "ID", Name: "id"},
+				{Field: "CustomerID", Name: "customer_id"},
+			},
+		},
+		{
+			TypeName: "customer",
+			VarName:  "CustomerColumns",
+			Columns: []column{
+				{Field: "ID", Name: "id"},
+				{Field: "NameFirst", Name: "name_first"},
+				{Field: "NameLast", Name: "name_last"},
+				{Field: "CreatedAt", Name: "created_at"},
+				{Field: "UpdatedAt", Name: "updated_at"},
+			},
+		},
+		{
+			TypeName: "profile",
+			VarName:  "ProfileColumns",
+			Columns: []column{
+				{Field: "ID", Name: "id"},
+			},
+		},
+		{
+			TypeName: "timestamps",
+			VarName:  "TimestampsColumns",
+			Columns: []column{
+				{Field: "CreatedAt", Name: "created_at"},
+				{Field: "UpdatedAt", Name: "updated_at"},
+			},
+		},
+	}, d.Models)
+
+	d, err = parseModelColumns(dir, []string{"profile"})
+	assert.NoError(err)
+	assert.Len(d.Models, 1)
+	assert.Equal("ProfileColumns", d.Models[0].VarName)
+
+	_, err = parseModelColumns(dir, []string{"missing"})
+	assert.Error(err)
+}
+
+func TestGenerateColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "storage.go"), []byte(columnsTestSrc), 0644)
+	assert.NoError(err)
+
+	out := filepath.Join(dir, "columns.go")
+
+	err = generateColumns(dir, "profile", out)
+	assert.NoError(err)
+
+	src, err := os.ReadFile(out)
+	assert.NoError(err)
+	assert.Equal(`// Code generated by storegen -columns. DO NOT EDIT.
+
+package storage
+
+import "github.com/eleanorhealth/milo"
+
+// ProfileColumns are the columns of profile.
+var ProfileColumns = struct {
+	ID milo.Column
+}{
+	ID: "id",
+}
+`, string(src))
+}
+
+func TestUnderscore(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("id", underscore("ID"))
+	assert.Equal("name_first", underscore("NameFirst"))
+	assert.Equal("customer_id", underscore("CustomerID"))
+	assert.Equal("httpurl", underscore("HTTPURL"))
+	assert.Equal("html_parser", underscore("HTMLParser"))
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	_ "embed"
 	"flag"
+	"go/format"
 	"log"
 	"os"
 	"strings"
@@ -15,6 +17,9 @@ var tpl string
 //go:embed template-tests
 var tplTests string
 
+//go:embed template-columns
+var tplColumns string
+
 type data struct {
 	EntityName        string
 	EntityType        string
@@ -24,17 +29,31 @@ type data struct {
 
 func main() {
 	var entityName, entityType, idType, notFoundErrorType string
-	var tests bool
+	var tests, columns bool
+	var dir, models, out string
 
 	flag.StringVar(&entityName, "entityName", "", "domain entity name (e.g., Customer)")
 	flag.StringVar(&entityType, "entityType", "", "domain entity type (e.g., *domain.Customer)")
 	flag.StringVar(&idType, "idType", "", "domain ID type (e.g., entityid.ID)")
-	flag.StringVar(&notFoundErrorType, "notFoundErrorType", "", "entity not found error type (e.g., domain.ErrNotFound)")
-	flag.BoolVar(&tests, "tests", false, "generate code for tests (assumes MockMiloStorer as the type for the mock milo.Storer)")
+	flag.StringVar(&notFoundErrorType, "notFoundErrorType", "", "entity not found error to return instead of milo's *milo.NotFoundError (e.g., domain.ErrNotFound)")
+	flag.BoolVar(&tests, "tests", false, "generate code for tests (uses mocks.Storer from github.com/eleanorhealth/milo/mocks as the mock milo.Storer)")
+	flag.BoolVar(&columns, "columns", false, "generate typed milo.Column identifiers for the storage models in -dir (e.g., CustomerColumns.NameFirst)")
+	flag.StringVar(&dir, "dir", ".", "directory of the storage package to read models from (used with -columns)")
+	flag.StringVar(&models, "models", "", "comma separated model struct names (used with -columns, defaults to every struct with pg tags)")
+	flag.StringVar(&out, "out", "", "file to write to (used with -columns, defaults to stdout)")
 
 	flag.Parse()
 
-	if len(entityName) == 0 || len(entityType) == 0 || len(idType) == 0 || len(notFoundErrorType) == 0 {
+	if columns {
+		err := generateColumns(dir, models, out)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if len(entityName) == 0 || len(entityType) == 0 || len(idType) == 0 {
 		flag.PrintDefaults()
 		os.Exit(0)
 	}
@@ -70,3 +89,40 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func generateColumns(dir string, models string, out string) error {
+	var modelNames []string
+	if len(models) > 0 {
+		modelNames = strings.Split(models, ",")
+	}
+
+	d, err := parseModelColumns(dir, modelNames)
+	if err != nil {
+		return err
+	}
+
+	t, err := template.New("template-columns").Parse(tplColumns)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+
+	err = t.Execute(buf, d)
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if len(out) > 0 {
+		return os.WriteFile(out, src, 0644)
+	}
+
+	_, err = os.Stdout.Write(src)
+
+	return err
+}
@@ -0,0 +1,10 @@
+package milo
+
+// Column is the name of a model's column. Expression functions accept a Column anywhere they accept a column name.
+// `storegen -columns` generates a Column for every field of a model (e.g., CustomerColumns.NameFirst), so renaming a
+// column breaks the build instead of a query.
+type Column string
+
+func (c Column) String() string {
+	return string(c)
+}
@@ -0,0 +1,38 @@
+package milo
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumn_String(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("name_first", Column("name_first").String())
+}
+
+func TestApplyExpressionsToQuery_Column(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		Equal(Column("name_first"), "Jane"),
+		Equal(Lower(Column("email")), "jane@example.com"),
+		OrderBy(Column("name_last")),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE (("expression_model"."name_first" = 'Jane' AND lower("expression_model"."email") = 'jane@example.com'))`)
+	assert.Contains(string(b), `ORDER BY "expression_model"."name_last" ASC`)
+
+	err = applyExpressionsToQuery([]Expression{Equal(Column("nme_first"), "Jane")}, orm.NewQuery(nil, &expressionModel{}))
+
+	var unknownErr *UnknownColumnError
+	assert.ErrorAs(err, &unknownErr)
+	assert.Equal("nme_first", unknownErr.Column)
+}
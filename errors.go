@@ -1,5 +1,205 @@
 package milo
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
 
 var ErrNotFound = errors.New("entity not found")
+
+// LockMode is the row lock a finder takes.
+type LockMode int
+
+const (
+	LockNone LockMode = iota
+	LockForUpdate
+	LockForUpdateSkipLocked
+)
+
+func lockMode(forUpdate bool, skipLocked bool) LockMode {
+	if !forUpdate {
+		return LockNone
+	}
+
+	if skipLocked {
+		return LockForUpdateSkipLocked
+	}
+
+	return LockForUpdate
+}
+
+func (l LockMode) String() string {
+	switch l {
+	case LockForUpdate:
+		return "FOR UPDATE"
+	case LockForUpdateSkipLocked:
+		return "FOR UPDATE SKIP LOCKED"
+	default:
+		return ""
+	}
+}
+
+// NotFoundError is returned by the finders when no entity matches. errors.Is(err, milo.ErrNotFound) is true for it.
+type NotFoundError struct {
+	EntityType reflect.Type
+	// ID is the ID passed to FindByID or FindByIDForUpdate, or nil.
+	ID interface{}
+	// Expressions are the expressions passed to FindOneBy or FindOneByForUpdate.
+	Expressions []Expression
+	Lock        LockMode
+}
+
+// NewNotFoundError returns a NotFoundError for the entity type of entity. Storer implementations other than Store
+// use it so their errors match Store's.
+func NewNotFoundError(entity interface{}, id interface{}, exprs []Expression, lock LockMode) *NotFoundError {
+	return &NotFoundError{
+		EntityType:  reflect.TypeOf(entity),
+		ID:          id,
+		Expressions: exprs,
+		Lock:        lock,
+	}
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("%s: %s", ErrNotFound, e.EntityType)
+
+	if e.ID != nil {
+		msg += fmt.Sprintf(" with ID %v", e.ID)
+	} else if len(e.Expressions) > 0 {
+		var conditions []Expression
+		var orders []string
+
+		for _, expr := range e.Expressions {
+			if expr.t == expressionTypeOrder {
+				orders = append(orders, expr.String())
+			} else {
+				conditions = append(conditions, expr)
+			}
+		}
+
+		if len(conditions) > 0 {
+			msg += " where " + And(conditions...).String()
+		}
+
+		if len(orders) > 0 {
+			msg += " " + strings.Join(orders, " ")
+		}
+	}
+
+	if e.Lock != LockNone {
+		msg += fmt.Sprintf(" (%s)", e.Lock)
+	}
+
+	return msg
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// UnknownColumnError is returned when an expression references a column that the model doesn't have.
+type UnknownColumnError struct {
+	Model       string
+	Column      string
+	Suggestions []string
+}
+
+func (e *UnknownColumnError) Error() string {
+	msg := fmt.Sprintf("unknown column %q for model %s", e.Column, e.Model)
+
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(e.Suggestions, ", "))
+	}
+
+	return msg
+}
+
+var (
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrLockNotAvailable     = errors.New("lock not available")
+)
+
+// dbErrorKinds maps SQLSTATE codes to the errors DBError.Kind can be.
+var dbErrorKinds = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23514": ErrCheckViolation,
+	"23502": ErrNotNullViolation,
+	"40001": ErrSerializationFailure,
+	"55P03": ErrLockNotAvailable,
+}
+
+// DBError is returned by the store for the common failures of the database, e.g., a unique violation. errors.Is
+// reports whether it is of a kind:
+//
+//	if errors.Is(err, milo.ErrUniqueViolation) {
+//
+// and errors.As gives the details:
+//
+//	var dbErr *milo.DBError
+//	if errors.As(err, &dbErr) {
+type DBError struct {
+	// Kind is one of ErrUniqueViolation, ErrForeignKeyViolation, ErrCheckViolation, ErrNotNullViolation,
+	// ErrSerializationFailure and ErrLockNotAvailable.
+	Kind error
+
+	// Constraint, Table and Column are set by the database when they apply, e.g., Column for a not null violation.
+	Constraint string
+	Table      string
+	Column     string
+	// Index is the index named Constraint declared by a model (see Indexer) for a unique violation, or nil if no
+	// model declares it.
+	Index *Index
+
+	err pg.Error
+}
+
+func (e *DBError) Error() string {
+	if e.Index != nil {
+		return fmt.Sprintf("%s of index %s (%s) of table %s", e.Kind, e.Index.Name, strings.Join(e.Index.Columns, ", "), e.Table)
+	}
+
+	var details []string
+
+	if e.Table != "" {
+		details = append(details, "table "+e.Table)
+	}
+
+	if e.Constraint != "" {
+		details = append(details, "constraint "+e.Constraint)
+	}
+
+	if e.Column != "" {
+		details = append(details, "column "+e.Column)
+	}
+
+	msg := e.Kind.Error()
+
+	if len(details) > 0 {
+		msg += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+
+	// A DBError built by hand, e.g., returned by a mock, has no pg.Error.
+	if e.err == nil {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", msg, e.err.Field('M'))
+}
+
+func (e *DBError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the pg.Error.
+func (e *DBError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,240 @@
+package milo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// pgError is a pg.Error with the given fields.
+type pgError map[byte]string
+
+func (e pgError) Error() string {
+	return "ERROR #" + e['C'] + " " + e['M']
+}
+
+func (e pgError) Field(field byte) string {
+	return e[field]
+}
+
+func (e pgError) IntegrityViolation() bool {
+	return e['C'][:2] == "23"
+}
+
+var _ pg.Error = pgError(nil)
+
+func TestDBError(t *testing.T) {
+	tests := []struct {
+		name        string
+		pgErr       pgError
+		kind        error
+		expectedErr string
+	}{
+		{
+			name:        "unique violation of declared index",
+			pgErr:       pgError{'C': "23505", 't': "accounts", 'n': "accounts_lower_email_key", 'M': "duplicate key value violates unique constraint \"accounts_lower_email_key\""},
+			kind:        ErrUniqueViolation,
+			expectedErr: "unique violation of index accounts_lower_email_key (lower(email)) of table accounts",
+		},
+		{
+			name:        "unique violation",
+			pgErr:       pgError{'C': "23505", 't': "accounts", 'n': "accounts_pkey", 'M': "duplicate key value violates unique constraint \"accounts_pkey\""},
+			kind:        ErrUniqueViolation,
+			expectedErr: "unique violation (table accounts, constraint accounts_pkey): duplicate key value violates unique constraint \"accounts_pkey\"",
+		},
+		{
+			name:        "foreign key violation",
+			pgErr:       pgError{'C': "23503", 't': "addresses", 'n': "addresses_user_id_fkey", 'M': "insert or update on table \"addresses\" violates foreign key constraint \"addresses_user_id_fkey\""},
+			kind:        ErrForeignKeyViolation,
+			expectedErr: "foreign key violation (table addresses, constraint addresses_user_id_fkey): insert or update on table \"addresses\" violates foreign key constraint \"addresses_user_id_fkey\"",
+		},
+		{
+			name:        "check violation",
+			pgErr:       pgError{'C': "23514", 't': "accounts", 'n': "accounts_quantity_check", 'M': "new row for relation \"accounts\" violates check constraint \"accounts_quantity_check\""},
+			kind:        ErrCheckViolation,
+			expectedErr: "check violation (table accounts, constraint accounts_quantity_check): new row for relation \"accounts\" violates check constraint \"accounts_quantity_check\"",
+		},
+		{
+			name:        "not null violation",
+			pgErr:       pgError{'C': "23502", 't': "accounts", 'c': "email", 'M': "null value in column \"email\" violates not-null constraint"},
+			kind:        ErrNotNullViolation,
+			expectedErr: "not null violation (table accounts, column email): null value in column \"email\" violates not-null constraint",
+		},
+		{
+			name:        "serialization failure",
+			pgErr:       pgError{'C': "40001", 'M': "could not serialize access due to concurrent update"},
+			kind:        ErrSerializationFailure,
+			expectedErr: "serialization failure: could not serialize access due to concurrent update",
+		},
+		{
+			name:        "lock not available",
+			pgErr:       pgError{'C': "55P03", 'M': "could not obtain lock on row in relation \"accounts\""},
+			kind:        ErrLockNotAvailable,
+			expectedErr: "lock not available: could not obtain lock on row in relation \"accounts\"",
+		},
+	}
+
+	store, err := NewStore(nil, EntityModelMap{
+		reflect.TypeOf(&accountEntity{}): reflect.TypeOf(&accountModel{}),
+	})
+	assert.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := errors.Wrap(store.translateError(tt.pgErr), "inserting model")
+			assert.EqualError(err, "inserting model: "+tt.expectedErr)
+			assert.ErrorIs(err, tt.kind)
+
+			var pgErr pg.Error
+			if assert.ErrorAs(err, &pgErr) {
+				assert.Equal(tt.pgErr['C'], pgErr.Field('C'))
+			}
+
+			var dbErr *DBError
+			if assert.ErrorAs(err, &dbErr) {
+				assert.Equal(tt.pgErr['n'], dbErr.Constraint)
+				assert.Equal(tt.pgErr['t'], dbErr.Table)
+				assert.Equal(tt.pgErr['c'], dbErr.Column)
+			}
+
+			// Translating twice doesn't wrap twice.
+			assert.Equal(err, store.translateError(err))
+		})
+	}
+
+	err = pgError{'C': "42P01", 'M': "relation \"accounts\" does not exist"}
+	assert.Equal(t, err, store.translateError(err))
+
+	err = errors.New("connection refused")
+	assert.Equal(t, err, store.translateError(err))
+}
+
+func TestDBError_WithoutPGError(t *testing.T) {
+	assert := assert.New(t)
+
+	// E.g., returned by a mocks.Storer.
+	err := &DBError{Kind: ErrUniqueViolation, Table: "accounts"}
+	assert.EqualError(err, "unique violation (table accounts)")
+	assert.ErrorIs(err, ErrUniqueViolation)
+	assert.Nil(err.Unwrap())
+
+	err = &DBError{Kind: ErrSerializationFailure}
+	assert.EqualError(err, "serialization failure")
+}
+
+func TestNotFoundError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         *NotFoundError
+		expectedErr string
+	}{
+		{
+			name:        "ID",
+			err:         NewNotFoundError(&userEntityPtr{}, "123", nil, LockNone),
+			expectedErr: "entity not found: *milo.userEntityPtr with ID 123",
+		},
+		{
+			name:        "ID for update",
+			err:         NewNotFoundError(&userEntityPtr{}, "123", nil, LockForUpdateSkipLocked),
+			expectedErr: "entity not found: *milo.userEntityPtr with ID 123 (FOR UPDATE SKIP LOCKED)",
+		},
+		{
+			name: "expressions",
+			err: NewNotFoundError(&userEntityPtr{}, nil, []Expression{
+				Equal("name_first", "Jane"),
+				Or(Equal("state", "MA"), Equal("state", "NY")),
+				OrderByDesc("created_at"),
+			}, LockForUpdate),
+			expectedErr: `entity not found: *milo.userEntityPtr where ("name_first" = 'Jane' AND ("state" = 'MA' OR "state" = 'NY')) ORDER BY "created_at" DESC (FOR UPDATE)`,
+		},
+		{
+			name:        "no expressions",
+			err:         NewNotFoundError(&userEntityPtr{}, nil, nil, LockNone),
+			expectedErr: "entity not found: *milo.userEntityPtr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.EqualError(tt.err, tt.expectedErr)
+			assert.ErrorIs(tt.err, ErrNotFound)
+			assert.ErrorIs(errors.Wrap(tt.err, "finding user"), ErrNotFound)
+			assert.NotErrorIs(tt.err, ErrUniqueViolation)
+		})
+	}
+}
+
+func TestStore_DBError(t *testing.T) {
+	assert := assert.New(t)
+
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err := createSchema(db)
+	assert.NoError(err)
+
+	store, err := NewStore(db, EntityModelMap{
+		reflect.TypeOf(&userEntityPtr{}): reflect.TypeOf(&userModelPtr{}),
+	})
+	assert.NoError(err)
+
+	user := &userEntityPtr{ID: uuid.New().String()}
+
+	err = store.Save(ctx, user)
+	assert.NoError(err)
+
+	// The foreign key is deferred, so the violation is reported when committing.
+	_, err = db.Exec("UPDATE users SET profile_id = ? WHERE id = ?", uuid.New().String(), user.ID)
+	assert.Error(err)
+
+	err = store.Transaction(ctx, func(txStore Storer) error {
+		_, err := txStore.(*Store).db.Exec("UPDATE users SET profile_id = ? WHERE id = ?", uuid.New().String(), user.ID)
+		return err
+	})
+	assert.ErrorIs(err, ErrForeignKeyViolation)
+
+	var dbErr *DBError
+	if assert.ErrorAs(err, &dbErr) {
+		assert.Equal("users", dbErr.Table)
+		assert.Equal("users_profile_id_fkey", dbErr.Constraint)
+	}
+
+	err = store.Transaction(ctx, func(txStore Storer) error {
+		_, err := txStore.(*Store).db.Exec("SET LOCAL lock_timeout = '1ms'")
+		if err != nil {
+			return err
+		}
+
+		// Locked by another transaction.
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		_, err = tx.Exec("SELECT * FROM users WHERE id = ? FOR UPDATE", user.ID)
+		if err != nil {
+			return err
+		}
+
+		return txStore.FindByIDForUpdate(ctx, &userEntityPtr{}, user.ID, false)
+	})
+	assert.ErrorIs(err, ErrLockNotAvailable)
+}
@@ -22,7 +22,9 @@ func main() {
 	})
 	defer db.Close()
 
-	err := storage.CreateSchema(db)
+	err := milo.CreateSchema(context.Background(), db, storage.MiloEntityModelMap, &milo.CreateSchemaOptions{
+		DropFirst: true,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
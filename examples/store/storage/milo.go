@@ -11,3 +11,5 @@ import (
 var MiloEntityModelMap = milo.EntityModelMap{
 	reflect.TypeOf(&domain.Customer{}): reflect.TypeOf(&customer{}),
 }
+
+//go:generate go run github.com/eleanorhealth/milo/cmd/storegen -columns -out columns.go
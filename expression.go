@@ -16,16 +16,28 @@ const (
 type expressionType int
 
 const (
-	expressionTypeAnd expressionType = iota
+	expressionTypeCondition expressionType = iota
+	expressionTypeAnd
 	expressionTypeOr
+	expressionTypeOrder
+	expressionTypeRaw
 )
 
+const (
+	orderAsc  Op = "ASC"
+	orderDesc Op = "DESC"
+)
+
+// Expression is a node in a boolean expression tree. A condition compares a column to a value. A group (see And and Or)
+// owns its connector and joins its child expressions with it, so the tree's shape is the precedence of the rendered SQL.
+// An order (see OrderBy and OrderByDesc) sorts results instead of filtering them and is only valid at the top level.
 type Expression struct {
 	column interface{}
 	op     Op
 	value  interface{}
 	t      expressionType
 	exprs  []Expression
+	params []interface{}
 }
 
 func (e Expression) Column() interface{} {
@@ -40,22 +52,20 @@ func (e Expression) Value() interface{} {
 	return e.value
 }
 
+// And returns a group that is true when all of exprs are true. An empty group is always true.
 func And(exprs ...Expression) Expression {
-	for i, expr := range exprs {
-		expr.t = expressionTypeAnd
-		exprs[i] = expr
+	return Expression{
+		t:     expressionTypeAnd,
+		exprs: exprs,
 	}
-
-	return Expression{exprs: exprs}
 }
 
+// Or returns a group that is true when any of exprs is true. An empty group is always false.
 func Or(exprs ...Expression) Expression {
-	for i, expr := range exprs {
-		expr.t = expressionTypeOr
-		exprs[i] = expr
+	return Expression{
+		t:     expressionTypeOr,
+		exprs: exprs,
 	}
-
-	return Expression{exprs: exprs}
 }
 
 func Equal(column interface{}, value interface{}) Expression {
@@ -63,7 +73,6 @@ func Equal(column interface{}, value interface{}) Expression {
 		column: column,
 		op:     OpEqual,
 		value:  value,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -72,7 +81,6 @@ func NotEqual(column interface{}, value interface{}) Expression {
 		column: column,
 		op:     OpNotEqual,
 		value:  value,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -81,7 +89,6 @@ func Gt(column interface{}, value interface{}) Expression {
 		column: column,
 		op:     OpGt,
 		value:  value,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -90,7 +97,6 @@ func Lt(column interface{}, value interface{}) Expression {
 		column: column,
 		op:     OpLt,
 		value:  value,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -99,7 +105,6 @@ func Gte(column interface{}, value interface{}) Expression {
 		column: column,
 		op:     OpGte,
 		value:  value,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -108,7 +113,6 @@ func Lte(column interface{}, value interface{}) Expression {
 		column: column,
 		op:     OpLte,
 		value:  value,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -117,7 +121,6 @@ func IsNull(column interface{}) Expression {
 		column: column,
 		op:     OpIsNull,
 		value:  nil,
-		t:      expressionTypeAnd,
 	}
 }
 
@@ -126,6 +129,23 @@ func IsNotNull(column interface{}) Expression {
 		column: column,
 		op:     OpIsNotNull,
 		value:  nil,
-		t:      expressionTypeAnd,
+	}
+}
+
+// OrderBy orders results by column in ascending order. Orders are applied in the order they are passed to a finder.
+func OrderBy(column interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     orderAsc,
+		t:      expressionTypeOrder,
+	}
+}
+
+// OrderByDesc orders results by column in descending order.
+func OrderByDesc(column interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     orderDesc,
+		t:      expressionTypeOrder,
 	}
 }
@@ -0,0 +1,46 @@
+package milo
+
+import "github.com/go-pg/pg/v10"
+
+const (
+	OpContainedBy Op = "<@"
+	OpOverlaps    Op = "&&"
+	OpAnyEqual    Op = "= ANY"
+)
+
+// ArrayContains matches rows where the array column contains every element of values (@>). values must be a slice.
+func ArrayContains(column interface{}, values interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     OpContains,
+		value:  pg.Array(values),
+	}
+}
+
+// ArrayContainedBy matches rows where every element of the array column is in values (<@). values must be a slice.
+func ArrayContainedBy(column interface{}, values interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     OpContainedBy,
+		value:  pg.Array(values),
+	}
+}
+
+// ArrayOverlaps matches rows where the array column and values have any element in common (&&). values must be a
+// slice.
+func ArrayOverlaps(column interface{}, values interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     OpOverlaps,
+		value:  pg.Array(values),
+	}
+}
+
+// AnyEqual matches rows where any element of the array column equals value (value = ANY(column)).
+func AnyEqual(column interface{}, value interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     OpAnyEqual,
+		value:  value,
+	}
+}
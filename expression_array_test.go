@@ -0,0 +1,130 @@
+package milo
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayContains(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := ArrayContains("foo", []string{"bar", "baz"})
+
+	assert.Equal("foo", actual.Column())
+	assert.Equal(OpContains, actual.Op())
+	assert.IsType(&types.Array{}, actual.Value())
+	assert.Equal([]string{"bar", "baz"}, actual.Value().(*types.Array).Value())
+}
+
+func TestArrayContainedBy(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := ArrayContainedBy("foo", []string{"bar", "baz"})
+
+	assert.Equal("foo", actual.Column())
+	assert.Equal(OpContainedBy, actual.Op())
+	assert.IsType(&types.Array{}, actual.Value())
+	assert.Equal([]string{"bar", "baz"}, actual.Value().(*types.Array).Value())
+}
+
+func TestArrayOverlaps(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := ArrayOverlaps("foo", []int{1, 2})
+
+	assert.Equal("foo", actual.Column())
+	assert.Equal(OpOverlaps, actual.Op())
+	assert.IsType(&types.Array{}, actual.Value())
+	assert.Equal([]int{1, 2}, actual.Value().(*types.Array).Value())
+}
+
+func TestAnyEqual(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := AnyEqual("foo", "bar")
+
+	assert.Equal(Expression{column: "foo", op: OpAnyEqual, value: "bar"}, actual)
+}
+
+func TestBuildCondition_Array(t *testing.T) {
+	tests := []struct {
+		name           string
+		expr           Expression
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:           "contains",
+			expr:           ArrayContains("tags", []string{"a", "b"}),
+			expectedSQL:    `t."tags" @> ?`,
+			expectedParams: []interface{}{[]string{"a", "b"}},
+		},
+		{
+			name:           "contained by",
+			expr:           ArrayContainedBy("tags", []string{"a", "b"}),
+			expectedSQL:    `t."tags" <@ ?`,
+			expectedParams: []interface{}{[]string{"a", "b"}},
+		},
+		{
+			name:           "overlaps",
+			expr:           ArrayOverlaps("tags", []string{"a", "b"}),
+			expectedSQL:    `t."tags" && ?`,
+			expectedParams: []interface{}{[]string{"a", "b"}},
+		},
+		{
+			name:           "any equal",
+			expr:           AnyEqual("tags", "a"),
+			expectedSQL:    `? = ANY(t."tags")`,
+			expectedParams: []interface{}{"a"},
+		},
+		{
+			name:           "and",
+			expr:           And(ArrayContains("tags", []string{"a"}), AnyEqual("codes", "F10.20")),
+			expectedSQL:    `(t."tags" @> ? AND ? = ANY(t."codes"))`,
+			expectedParams: []interface{}{[]string{"a"}, "F10.20"},
+		},
+		{
+			name:           "or",
+			expr:           Or(ArrayOverlaps("tags", []string{"a"}), And(ArrayContainedBy("tags", []string{"b", "c"}), Equal("name", "d"))),
+			expectedSQL:    `(t."tags" && ? OR (t."tags" <@ ? AND t."name" = ?))`,
+			expectedParams: []interface{}{[]string{"a"}, []string{"b", "c"}, "d"},
+		},
+		{
+			name:           "mixed with jsonb",
+			expr:           And(ArrayContains("tags", []string{"a"}), JSONContains("answers", []string{"a"})),
+			expectedSQL:    `(t."tags" @> ? AND t."answers" @> ?)`,
+			expectedParams: []interface{}{[]string{"a"}, `["a"]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+			assert.Equal(tt.expectedSQL, sql)
+			assert.Equal(tt.expectedParams, unwrapArrayParams(params))
+		})
+	}
+}
+
+func TestApplyExpressionsToQuery_Array(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		ArrayContains("tags", []string{"a", "b"}),
+		Or(ArrayContainedBy("tags", []string{"c"}), ArrayOverlaps("codes", []int{1, 2})),
+		AnyEqual("tags", "it's"),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE (("expression_model"."tags" @> '{"a","b"}' AND ("expression_model"."tags" <@ '{"c"}' OR "expression_model"."codes" && '{1,2}') AND 'it''s' = ANY("expression_model"."tags")))`)
+}
@@ -0,0 +1,249 @@
+package milo
+
+// Col returns name as a Column so expressions can be built fluently, e.g.,
+// Col("name_first").Eq("Jane").And(Col("state").Eq("MA")). Each method returns the same Expression as the function of
+// the same name, so the two styles can be mixed.
+func Col(name string) Column {
+	return Column(name)
+}
+
+// Eq is Equal(c, value).
+func (c Column) Eq(value interface{}) Expression {
+	return Equal(c, value)
+}
+
+// NotEq is NotEqual(c, value).
+func (c Column) NotEq(value interface{}) Expression {
+	return NotEqual(c, value)
+}
+
+// Gt is Gt(c, value).
+func (c Column) Gt(value interface{}) Expression {
+	return Gt(c, value)
+}
+
+// Lt is Lt(c, value).
+func (c Column) Lt(value interface{}) Expression {
+	return Lt(c, value)
+}
+
+// Gte is Gte(c, value).
+func (c Column) Gte(value interface{}) Expression {
+	return Gte(c, value)
+}
+
+// Lte is Lte(c, value).
+func (c Column) Lte(value interface{}) Expression {
+	return Lte(c, value)
+}
+
+// IsNull is IsNull(c).
+func (c Column) IsNull() Expression {
+	return IsNull(c)
+}
+
+// IsNotNull is IsNotNull(c).
+func (c Column) IsNotNull() Expression {
+	return IsNotNull(c)
+}
+
+// ArrayContains is ArrayContains(c, values).
+func (c Column) ArrayContains(values interface{}) Expression {
+	return ArrayContains(c, values)
+}
+
+// ArrayContainedBy is ArrayContainedBy(c, values).
+func (c Column) ArrayContainedBy(values interface{}) Expression {
+	return ArrayContainedBy(c, values)
+}
+
+// ArrayOverlaps is ArrayOverlaps(c, values).
+func (c Column) ArrayOverlaps(values interface{}) Expression {
+	return ArrayOverlaps(c, values)
+}
+
+// AnyEqual is AnyEqual(c, value).
+func (c Column) AnyEqual(value interface{}) Expression {
+	return AnyEqual(c, value)
+}
+
+// JSONPath is JSONPath(c, path...).
+func (c Column) JSONPath(path ...interface{}) JSONPathColumn {
+	return JSONPath(c, path...)
+}
+
+// JSONContains is JSONContains(c, value).
+func (c Column) JSONContains(value interface{}) Expression {
+	return JSONContains(c, value)
+}
+
+// JSONHasKey is JSONHasKey(c, key).
+func (c Column) JSONHasKey(key string) Expression {
+	return JSONHasKey(c, key)
+}
+
+// JSONHasAnyKeys is JSONHasAnyKeys(c, keys...).
+func (c Column) JSONHasAnyKeys(keys ...string) Expression {
+	return JSONHasAnyKeys(c, keys...)
+}
+
+// JSONHasAllKeys is JSONHasAllKeys(c, keys...).
+func (c Column) JSONHasAllKeys(keys ...string) Expression {
+	return JSONHasAllKeys(c, keys...)
+}
+
+// JSONPathMatches is JSONPathMatches(c, path).
+func (c Column) JSONPathMatches(path string) Expression {
+	return JSONPathMatches(c, path)
+}
+
+// Matches is Matches([]interface{}{c}, query, config).
+func (c Column) Matches(query string, config string) Expression {
+	return Matches([]interface{}{c}, query, config)
+}
+
+// MatchesVector is MatchesVector(c, query, config).
+func (c Column) MatchesVector(query string, config string) Expression {
+	return MatchesVector(c, query, config)
+}
+
+// Asc is OrderBy(c).
+func (c Column) Asc() Expression {
+	return OrderBy(c)
+}
+
+// Desc is OrderByDesc(c).
+func (c Column) Desc() Expression {
+	return OrderByDesc(c)
+}
+
+// The comparison methods of JSONPathColumn and FuncColumn continue a chain like Col("answers").JSONPath("a").Eq(1).
+
+// Eq is Equal(c, value).
+func (c JSONPathColumn) Eq(value interface{}) Expression {
+	return Equal(c, value)
+}
+
+// NotEq is NotEqual(c, value).
+func (c JSONPathColumn) NotEq(value interface{}) Expression {
+	return NotEqual(c, value)
+}
+
+// Gt is Gt(c, value).
+func (c JSONPathColumn) Gt(value interface{}) Expression {
+	return Gt(c, value)
+}
+
+// Lt is Lt(c, value).
+func (c JSONPathColumn) Lt(value interface{}) Expression {
+	return Lt(c, value)
+}
+
+// Gte is Gte(c, value).
+func (c JSONPathColumn) Gte(value interface{}) Expression {
+	return Gte(c, value)
+}
+
+// Lte is Lte(c, value).
+func (c JSONPathColumn) Lte(value interface{}) Expression {
+	return Lte(c, value)
+}
+
+// IsNull is IsNull(c).
+func (c JSONPathColumn) IsNull() Expression {
+	return IsNull(c)
+}
+
+// IsNotNull is IsNotNull(c).
+func (c JSONPathColumn) IsNotNull() Expression {
+	return IsNotNull(c)
+}
+
+// Asc is OrderBy(c).
+func (c JSONPathColumn) Asc() Expression {
+	return OrderBy(c)
+}
+
+// Desc is OrderByDesc(c).
+func (c JSONPathColumn) Desc() Expression {
+	return OrderByDesc(c)
+}
+
+// Eq is Equal(c, value).
+func (c FuncColumn) Eq(value interface{}) Expression {
+	return Equal(c, value)
+}
+
+// NotEq is NotEqual(c, value).
+func (c FuncColumn) NotEq(value interface{}) Expression {
+	return NotEqual(c, value)
+}
+
+// Gt is Gt(c, value).
+func (c FuncColumn) Gt(value interface{}) Expression {
+	return Gt(c, value)
+}
+
+// Lt is Lt(c, value).
+func (c FuncColumn) Lt(value interface{}) Expression {
+	return Lt(c, value)
+}
+
+// Gte is Gte(c, value).
+func (c FuncColumn) Gte(value interface{}) Expression {
+	return Gte(c, value)
+}
+
+// Lte is Lte(c, value).
+func (c FuncColumn) Lte(value interface{}) Expression {
+	return Lte(c, value)
+}
+
+// IsNull is IsNull(c).
+func (c FuncColumn) IsNull() Expression {
+	return IsNull(c)
+}
+
+// IsNotNull is IsNotNull(c).
+func (c FuncColumn) IsNotNull() Expression {
+	return IsNotNull(c)
+}
+
+// Asc is OrderBy(c).
+func (c FuncColumn) Asc() Expression {
+	return OrderBy(c)
+}
+
+// Desc is OrderByDesc(c).
+func (c FuncColumn) Desc() Expression {
+	return OrderByDesc(c)
+}
+
+// And returns a group that is true when e and all of exprs are true. If e is already an And group, exprs are added to
+// a copy of it, so Col("a").Eq(1).And(x).And(y) is the same as And(Equal(Col("a"), 1), x, y).
+func (e Expression) And(exprs ...Expression) Expression {
+	return e.group(expressionTypeAnd, exprs)
+}
+
+// Or returns a group that is true when e or any of exprs is true. If e is already an Or group, exprs are added to a
+// copy of it.
+func (e Expression) Or(exprs ...Expression) Expression {
+	return e.group(expressionTypeOr, exprs)
+}
+
+func (e Expression) group(t expressionType, exprs []Expression) Expression {
+	var grouped []Expression
+
+	if e.t == t {
+		grouped = make([]Expression, 0, len(e.exprs)+len(exprs))
+		grouped = append(grouped, e.exprs...)
+	} else {
+		grouped = make([]Expression, 0, len(exprs)+1)
+		grouped = append(grouped, e)
+	}
+
+	return Expression{
+		t:     t,
+		exprs: append(grouped, exprs...),
+	}
+}
@@ -0,0 +1,152 @@
+package milo
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCol(t *testing.T) {
+	assert := assert.New(t)
+
+	c := Col("foo")
+
+	assert.Equal(Column("foo"), c)
+
+	assert.Equal(Equal(c, 1), c.Eq(1))
+	assert.Equal(NotEqual(c, 1), c.NotEq(1))
+	assert.Equal(Gt(c, 1), c.Gt(1))
+	assert.Equal(Lt(c, 1), c.Lt(1))
+	assert.Equal(Gte(c, 1), c.Gte(1))
+	assert.Equal(Lte(c, 1), c.Lte(1))
+	assert.Equal(IsNull(c), c.IsNull())
+	assert.Equal(IsNotNull(c), c.IsNotNull())
+	assert.Equal(AnyEqual(c, "a"), c.AnyEqual("a"))
+	assert.Equal(JSONPath(c, "a", 0), c.JSONPath("a", 0))
+	assert.Equal(JSONContains(c, map[string]string{"a": "b"}), c.JSONContains(map[string]string{"a": "b"}))
+	assert.Equal(JSONHasKey(c, "a"), c.JSONHasKey("a"))
+	assert.Equal(JSONPathMatches(c, "$.a"), c.JSONPathMatches("$.a"))
+	assert.Equal(Matches([]interface{}{c}, "jane", "english"), c.Matches("jane", "english"))
+	assert.Equal(MatchesVector(c, "jane", "english"), c.MatchesVector("jane", "english"))
+	assert.Equal(OrderBy(c), c.Asc())
+	assert.Equal(OrderByDesc(c), c.Desc())
+
+	for _, tt := range []struct {
+		expected Expression
+		actual   Expression
+	}{
+		{ArrayContains(c, []string{"a"}), c.ArrayContains([]string{"a"})},
+		{ArrayContainedBy(c, []string{"a"}), c.ArrayContainedBy([]string{"a"})},
+		{ArrayOverlaps(c, []string{"a"}), c.ArrayOverlaps([]string{"a"})},
+		{JSONHasAnyKeys(c, "a", "b"), c.JSONHasAnyKeys("a", "b")},
+		{JSONHasAllKeys(c, "a", "b"), c.JSONHasAllKeys("a", "b")},
+	} {
+		expectedSQL, expectedParams, err := buildCondition("t", tt.expected)
+		assert.NoError(err)
+
+		actualSQL, actualParams, err := buildCondition("t", tt.actual)
+		assert.NoError(err)
+
+		assert.Equal(expectedSQL, actualSQL)
+		assert.Equal(unwrapArrayParams(expectedParams), unwrapArrayParams(actualParams))
+	}
+}
+
+func TestColumnReferenceMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	path := Col("answers").JSONPath("medications", 0)
+
+	assert.Equal(Equal(path, "aspirin"), path.Eq("aspirin"))
+	assert.Equal(NotEqual(path, "aspirin"), path.NotEq("aspirin"))
+	assert.Equal(Gt(path, 1), path.Gt(1))
+	assert.Equal(Lt(path, 1), path.Lt(1))
+	assert.Equal(Gte(path, 1), path.Gte(1))
+	assert.Equal(Lte(path, 1), path.Lte(1))
+	assert.Equal(IsNull(path), path.IsNull())
+	assert.Equal(IsNotNull(path), path.IsNotNull())
+	assert.Equal(OrderBy(path), path.Asc())
+	assert.Equal(OrderByDesc(path), path.Desc())
+
+	fn := Lower(Col("email"))
+
+	assert.Equal(Equal(fn, "jane@example.com"), fn.Eq("jane@example.com"))
+	assert.Equal(NotEqual(fn, "a"), fn.NotEq("a"))
+	assert.Equal(Gt(fn, "a"), fn.Gt("a"))
+	assert.Equal(Lt(fn, "a"), fn.Lt("a"))
+	assert.Equal(Gte(fn, "a"), fn.Gte("a"))
+	assert.Equal(Lte(fn, "a"), fn.Lte("a"))
+	assert.Equal(IsNull(fn), fn.IsNull())
+	assert.Equal(IsNotNull(fn), fn.IsNotNull())
+	assert.Equal(OrderBy(fn), fn.Asc())
+	assert.Equal(OrderByDesc(fn), fn.Desc())
+
+	sql, params, err := buildCondition("t", Col("answers").JSONPath("a").Eq(1).And(Lower(Col("email")).Eq("jane@example.com")))
+	assert.NoError(err)
+
+	expectedSQL, expectedParams, err := buildCondition("t", And(Equal(JSONPath("answers", "a"), 1), Equal(Lower("email"), "jane@example.com")))
+	assert.NoError(err)
+
+	assert.Equal(expectedSQL, sql)
+	assert.Equal(expectedParams, params)
+}
+
+func TestExpression_And(t *testing.T) {
+	assert := assert.New(t)
+
+	a := Col("a").Eq(1)
+	b := Col("b").Gt(2)
+	c := Col("c").IsNull()
+
+	assert.Equal(And(a, b), a.And(b))
+	assert.Equal(And(a, b, c), a.And(b).And(c))
+	assert.Equal(And(a, b, c), a.And(b, c))
+	assert.Equal(And(Or(a, b), c), a.Or(b).And(c))
+	assert.Equal(And(a), And().And(a))
+}
+
+func TestExpression_Or(t *testing.T) {
+	assert := assert.New(t)
+
+	a := Col("a").Eq(1)
+	b := Col("b").Gt(2)
+	c := Col("c").IsNull()
+
+	assert.Equal(Or(a, b), a.Or(b))
+	assert.Equal(Or(a, b, c), a.Or(b).Or(c))
+	assert.Equal(Or(And(a, b), c), a.And(b).Or(c))
+	assert.Equal(Or(a, And(b, c)), a.Or(b.And(c)))
+}
+
+func TestExpression_AndDoesNotModifyReceiver(t *testing.T) {
+	assert := assert.New(t)
+
+	a := Col("a").Eq(1)
+	base := And(a, Col("b").Eq(2))
+
+	x := base.And(Col("x").Eq(3))
+	y := base.And(Col("y").Eq(4))
+
+	assert.Len(base.exprs, 2)
+	assert.Equal(And(a, Col("b").Eq(2), Col("x").Eq(3)), x)
+	assert.Equal(And(a, Col("b").Eq(2), Col("y").Eq(4)), y)
+}
+
+func TestApplyExpressionsToQuery_Builder(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		Col("name_first").Eq("Jane").And(Col("name_last").NotEq("Doe").Or(Equal("email", "jane@example.com"))),
+		Col("tags").AnyEqual("a"),
+		Col("name_last").Desc(),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE ((("expression_model"."name_first" = 'Jane' AND ("expression_model"."name_last" != 'Doe' OR "expression_model"."email" = 'jane@example.com')) AND 'a' = ANY("expression_model"."tags")))`)
+	assert.Contains(string(b), `ORDER BY "expression_model"."name_last" DESC`)
+}
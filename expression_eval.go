@@ -0,0 +1,1237 @@
+package milo
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/pkg/errors"
+)
+
+// Matches reports whether model, a storage model or a pointer to one, would be selected by e in Postgres. Columns are
+// resolved through the model's pg tags, and zero values of fields without use_zero are NULL, as go-pg saves them.
+//
+// Conditions follow SQL's three-valued logic: a comparison with NULL is unknown, And and Or combine unknown results the
+// way Postgres does, and the model matches only if the result is true. Values are converted to the type of the column
+// they are compared to, like Postgres converts the literals go-pg renders; a value that can't be converted is an error.
+//
+// Matches approximates Postgres in a few places: strings are compared byte by byte (like the C collation), full-text
+// search only supports the simple configuration, and JSONPathMatches supports a subset of jsonpath in lax mode
+// (accessors, wildcards, and filters with comparisons, &&, ||, ! and exists). Raw expressions and orders can't be
+// evaluated.
+func (e Expression) Matches(model interface{}) (bool, error) {
+	strct := reflect.Indirect(reflect.ValueOf(model))
+	if strct.Kind() != reflect.Struct {
+		return false, fmt.Errorf("model must be a struct or a pointer to a struct, got %T", model)
+	}
+
+	table := orm.GetTable(strct.Type())
+
+	err := validateExpressions(table, []Expression{e})
+	if err != nil {
+		return false, err
+	}
+
+	ev := &evaluator{
+		table: table,
+		strct: strct,
+	}
+
+	r, err := ev.evalExpression(e)
+	if err != nil {
+		return false, err
+	}
+
+	return r == sqlTrue, nil
+}
+
+// SelectModels returns the models (storage models or pointers to them) that a query with exprs would select, in the
+// order it would return them: conditions are joined with AND (see Matches) and the models are sorted by the orders in
+// exprs, in the order they appear. Like in Postgres, NULLs sort last in ascending order and first in descending order.
+// Models that are equal for every order keep their relative order.
+func SelectModels(models []interface{}, exprs ...Expression) ([]interface{}, error) {
+	var conditions []Expression
+	var orders []Expression
+
+	for _, e := range exprs {
+		if e.t == expressionTypeOrder {
+			orders = append(orders, e)
+		} else {
+			conditions = append(conditions, e)
+		}
+	}
+
+	condition := And(conditions...)
+
+	type selectedModel struct {
+		model interface{}
+		keys  []interface{}
+	}
+
+	var selected []selectedModel
+
+	for _, model := range models {
+		matched, err := condition.Matches(model)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matched {
+			continue
+		}
+
+		strct := reflect.Indirect(reflect.ValueOf(model))
+		table := orm.GetTable(strct.Type())
+
+		err = validateExpressions(table, orders)
+		if err != nil {
+			return nil, err
+		}
+
+		ev := &evaluator{
+			table: table,
+			strct: strct,
+		}
+
+		keys := make([]interface{}, len(orders))
+
+		for i, order := range orders {
+			keys[i], err = ev.evalColumn(order.column)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		selected = append(selected, selectedModel{
+			model: model,
+			keys:  keys,
+		})
+	}
+
+	var sortErr error
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		for k, order := range orders {
+			a, b := selected[i].keys[k], selected[j].keys[k]
+
+			var c int
+
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				c = 1
+			case b == nil:
+				c = -1
+			default:
+				var err error
+
+				c, err = compareValues(a, b)
+				if err != nil && sortErr == nil {
+					sortErr = errors.Wrapf(err, "ordering by %v", order.column)
+				}
+			}
+
+			if order.op == orderDesc {
+				c = -c
+			}
+
+			if c != 0 {
+				return c < 0
+			}
+		}
+
+		return false
+	})
+
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	result := make([]interface{}, len(selected))
+	for i, s := range selected {
+		result[i] = s.model
+	}
+
+	return result, nil
+}
+
+// sqlBool is the result of a condition in SQL's three-valued logic.
+type sqlBool int
+
+const (
+	sqlFalse sqlBool = iota
+	sqlTrue
+	sqlUnknown
+)
+
+func toSQLBool(b bool) sqlBool {
+	if b {
+		return sqlTrue
+	}
+
+	return sqlFalse
+}
+
+// jsonbValue is the decoded value of a json or jsonb column (map[string]interface{}, []interface{}, string, float64,
+// bool or nil for JSON null), so it isn't confused with a SQL array or NULL.
+type jsonbValue struct {
+	v interface{}
+}
+
+// evaluator evaluates expressions against one model. Column values are normalized to nil (NULL), int64, float64,
+// string, bool, time.Time, []interface{} (arrays, whose elements are normalized too) or jsonbValue.
+type evaluator struct {
+	table *orm.Table
+	strct reflect.Value
+}
+
+func (ev *evaluator) evalExpression(e Expression) (sqlBool, error) {
+	switch e.t {
+	case expressionTypeCondition:
+		return ev.evalCondition(e)
+
+	case expressionTypeAnd:
+		result := sqlTrue
+
+		for _, expr := range e.exprs {
+			r, err := ev.evalExpression(expr)
+			if err != nil {
+				return sqlFalse, err
+			}
+
+			if r == sqlFalse {
+				result = sqlFalse
+			} else if r == sqlUnknown && result == sqlTrue {
+				result = sqlUnknown
+			}
+		}
+
+		return result, nil
+
+	case expressionTypeOr:
+		result := sqlFalse
+
+		for _, expr := range e.exprs {
+			r, err := ev.evalExpression(expr)
+			if err != nil {
+				return sqlFalse, err
+			}
+
+			if r == sqlTrue {
+				result = sqlTrue
+			} else if r == sqlUnknown && result == sqlFalse {
+				result = sqlUnknown
+			}
+		}
+
+		return result, nil
+
+	case expressionTypeOrder:
+		return sqlFalse, fmt.Errorf("order by %v can't be evaluated as a condition", e.column)
+
+	case expressionTypeRaw:
+		return sqlFalse, fmt.Errorf("raw expression %q can't be evaluated in memory", e.value)
+
+	default:
+		return sqlFalse, fmt.Errorf("unknown expressionType: %s", reflect.TypeOf(e.t).String())
+	}
+}
+
+func (ev *evaluator) evalCondition(e Expression) (sqlBool, error) {
+	if e.op == OpMatches {
+		return ev.evalMatches(e)
+	}
+
+	column, err := ev.evalColumn(e.column)
+	if err != nil {
+		return sqlFalse, err
+	}
+
+	switch e.op {
+	case OpIsNull:
+		return toSQLBool(column == nil), nil
+
+	case OpIsNotNull:
+		return toSQLBool(column != nil), nil
+
+	case OpEqual, OpNotEqual, OpGt, OpLt, OpGte, OpLte:
+		value, err := normalizeValue(reflect.ValueOf(e.value))
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		if column == nil || value == nil {
+			return sqlUnknown, nil
+		}
+
+		return compareOp(e.op, column, value)
+
+	case OpAnyEqual:
+		value, err := normalizeValue(reflect.ValueOf(e.value))
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		if column == nil {
+			return sqlUnknown, nil
+		}
+
+		elems, ok := column.([]interface{})
+		if !ok {
+			return sqlFalse, fmt.Errorf("= ANY requires an array column, got %s", describeValue(column))
+		}
+
+		if value == nil {
+			return sqlUnknown, nil
+		}
+
+		result := sqlFalse
+
+		for _, elem := range elems {
+			if elem == nil {
+				result = sqlUnknown
+				continue
+			}
+
+			r, err := compareOp(OpEqual, elem, value)
+			if err != nil {
+				return sqlFalse, err
+			}
+
+			if r == sqlTrue {
+				return sqlTrue, nil
+			}
+		}
+
+		return result, nil
+
+	case OpContains:
+		if column == nil {
+			return sqlUnknown, nil
+		}
+
+		if array, ok := e.value.(*types.Array); ok {
+			value, err := normalizeValue(reflect.ValueOf(array.Value()))
+			if err != nil {
+				return sqlFalse, err
+			}
+
+			return arrayContains(column, value)
+		}
+
+		doc, ok := column.(jsonbValue)
+		if !ok {
+			return sqlFalse, fmt.Errorf("@> requires a jsonb or array column, got %s", describeValue(column))
+		}
+
+		value, err := toJSONB(e.value)
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		return toSQLBool(jsonbContains(doc.v, value.v, true)), nil
+
+	case OpContainedBy, OpOverlaps:
+		if column == nil {
+			return sqlUnknown, nil
+		}
+
+		v := e.value
+		if array, ok := v.(*types.Array); ok {
+			v = array.Value()
+		}
+
+		value, err := normalizeValue(reflect.ValueOf(v))
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		if e.op == OpContainedBy {
+			return arrayContains(value, column)
+		}
+
+		return arrayOverlaps(column, value)
+
+	case OpHasKey, OpHasAnyKeys, OpHasAllKeys:
+		if column == nil {
+			return sqlUnknown, nil
+		}
+
+		doc, ok := column.(jsonbValue)
+		if !ok {
+			return sqlFalse, fmt.Errorf("%s requires a jsonb column, got %s", e.op, describeValue(column))
+		}
+
+		var keys []string
+
+		switch v := e.value.(type) {
+		case string:
+			keys = []string{v}
+		case []string:
+			keys = v
+		default:
+			return sqlFalse, fmt.Errorf("%s requires string keys, got %T", e.op, e.value)
+		}
+
+		found := 0
+		for _, key := range keys {
+			if jsonbHasKey(doc.v, key) {
+				found++
+			}
+		}
+
+		switch e.op {
+		case OpHasAllKeys:
+			return toSQLBool(found == len(keys)), nil
+		default:
+			return toSQLBool(found > 0), nil
+		}
+
+	case OpPathMatches:
+		if column == nil {
+			return sqlUnknown, nil
+		}
+
+		doc, ok := column.(jsonbValue)
+		if !ok {
+			return sqlFalse, fmt.Errorf("@? requires a jsonb column, got %s", describeValue(column))
+		}
+
+		path, ok := e.value.(string)
+		if !ok {
+			return sqlFalse, fmt.Errorf("@? requires a string jsonpath, got %T", e.value)
+		}
+
+		items, err := evalJSONPath(path, doc.v)
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		return toSQLBool(len(items) > 0), nil
+
+	default:
+		return sqlFalse, fmt.Errorf("operator %q can't be evaluated in memory", e.op)
+	}
+}
+
+// evalColumn returns the normalized value of a column reference.
+func (ev *evaluator) evalColumn(column interface{}) (interface{}, error) {
+	switch c := column.(type) {
+	case string:
+		return ev.evalField(c)
+
+	case Column:
+		return ev.evalField(string(c))
+
+	case JSONPathColumn:
+		return ev.evalJSONPathColumn(c)
+
+	case FuncColumn:
+		return ev.evalFuncColumn(c)
+
+	case TSVectorColumn, TSRankColumn:
+		return nil, fmt.Errorf("%T can only be evaluated by Matches", column)
+
+	default:
+		v := reflect.ValueOf(column)
+		if v.Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported column type %T", column)
+		}
+
+		return ev.evalField(v.String())
+	}
+}
+
+func (ev *evaluator) evalField(name string) (interface{}, error) {
+	field, v, err := ev.fieldValue(name)
+	if err != nil || !v.IsValid() {
+		return nil, err
+	}
+
+	switch field.SQLType {
+	case "jsonb", "json":
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+
+		return toJSONB(v.Interface())
+
+	case "tsvector":
+		return nil, fmt.Errorf("tsvector column %q can only be evaluated by MatchesVector", name)
+	}
+
+	return normalizeValue(v)
+}
+
+// fieldValue returns the field for the column name and its value, which is invalid if the column is NULL: if the field
+// is in a nil embedded struct or, without use_zero, has the zero value.
+func (ev *evaluator) fieldValue(name string) (*orm.Field, reflect.Value, error) {
+	field, ok := ev.table.FieldsMap[name]
+	if !ok {
+		return nil, reflect.Value{}, &UnknownColumnError{
+			Model:       ev.table.Type.String(),
+			Column:      name,
+			Suggestions: suggestColumns(ev.table, name),
+		}
+	}
+
+	if field.NullZero() && field.HasZeroValue(ev.strct) {
+		return field, reflect.Value{}, nil
+	}
+
+	// Unlike field.Value, this doesn't allocate nil embedded structs.
+	v := ev.strct
+	for _, i := range field.Index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return field, reflect.Value{}, nil
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	return field, v, nil
+}
+
+func (ev *evaluator) evalJSONPathColumn(c JSONPathColumn) (interface{}, error) {
+	if len(c.path) == 0 {
+		return nil, fmt.Errorf("json path for column %v must have at least one key or index", c.column)
+	}
+
+	column, err := ev.evalColumn(c.column)
+	if err != nil || column == nil {
+		return nil, err
+	}
+
+	doc, ok := column.(jsonbValue)
+	if !ok {
+		return nil, fmt.Errorf("json path requires a jsonb column, got %s", describeValue(column))
+	}
+
+	v := doc.v
+
+	for _, step := range c.path {
+		switch s := step.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, nil
+			}
+
+			v, ok = obj[s]
+			if !ok {
+				return nil, nil
+			}
+
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, nil
+			}
+
+			// Like Postgres, negative indexes count from the end of the array.
+			if s < 0 {
+				s += len(arr)
+			}
+
+			if s < 0 || s >= len(arr) {
+				return nil, nil
+			}
+
+			v = arr[s]
+
+		default:
+			return nil, fmt.Errorf("json path elements must be strings or ints, got %T", step)
+		}
+	}
+
+	// The last step is extracted as text (->>).
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+
+	case string:
+		return t, nil
+
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	}
+}
+
+func (ev *evaluator) evalFuncColumn(c FuncColumn) (interface{}, error) {
+	args := make([]interface{}, len(c.args))
+
+	for i, arg := range c.args {
+		var err error
+
+		if isColumn(arg) {
+			args[i], err = ev.evalColumn(arg)
+		} else {
+			if l, ok := arg.(LiteralValue); ok {
+				arg = l.value
+			}
+
+			args[i], err = normalizeValue(reflect.ValueOf(arg))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch strings.ToLower(c.name) {
+	case "lower", "upper":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes 1 argument, got %d", c.name, len(args))
+		}
+
+		if args[0] == nil {
+			return nil, nil
+		}
+
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s requires text, got %s", c.name, describeValue(args[0]))
+		}
+
+		if strings.EqualFold(c.name, "lower") {
+			return strings.ToLower(s), nil
+		}
+
+		return strings.ToUpper(s), nil
+
+	case "coalesce":
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+
+		return nil, nil
+
+	case "date_trunc":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("date_trunc takes 2 arguments, got %d", len(args))
+		}
+
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+
+		field, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("date_trunc requires a text field, got %s", describeValue(args[0]))
+		}
+
+		t, ok := args[1].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("date_trunc requires a timestamp, got %s", describeValue(args[1]))
+		}
+
+		return dateTrunc(field, t)
+
+	default:
+		return nil, fmt.Errorf("function %s can't be evaluated in memory", c.name)
+	}
+}
+
+// dateTrunc truncates t in UTC, which matches Postgres for timestamptz columns when the session time zone is UTC.
+func dateTrunc(field string, t time.Time) (time.Time, error) {
+	t = t.UTC()
+
+	switch strings.ToLower(field) {
+	case "microseconds":
+		return t.Truncate(time.Microsecond), nil
+	case "milliseconds":
+		return t.Truncate(time.Millisecond), nil
+	case "second":
+		return t.Truncate(time.Second), nil
+	case "minute":
+		return t.Truncate(time.Minute), nil
+	case "hour":
+		return t.Truncate(time.Hour), nil
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case "week":
+		// Weeks start on Monday.
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case "quarter":
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC), nil
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("date_trunc field %q can't be evaluated in memory", field)
+	}
+}
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	valuerType         = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonRawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	typesArrayType     = reflect.TypeOf((*types.Array)(nil))
+	byteSliceType      = reflect.TypeOf([]byte(nil))
+)
+
+// normalizeValue converts a Go value to the representation the evaluator compares. Nil pointers and nil interfaces are
+// NULL.
+func normalizeValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		if v.Type() == typesArrayType {
+			return normalizeValue(reflect.ValueOf(v.Interface().(*types.Array).Value()))
+		}
+
+		if v.Type().Implements(valuerType) {
+			break
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time), nil
+	}
+
+	if v.Type().Implements(valuerType) {
+		value, err := v.Interface().(driver.Valuer).Value()
+		if err != nil {
+			return nil, err
+		}
+
+		return normalizeValue(reflect.ValueOf(value))
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Bool:
+		return v.Bool(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return float64(u), nil
+		}
+
+		return int64(u), nil
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Type() == byteSliceType || v.Type() == jsonRawMessageType {
+			return nil, fmt.Errorf("unsupported value type %s", v.Type())
+		}
+
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		elems := make([]interface{}, v.Len())
+
+		for i := range elems {
+			elem, err := normalizeValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			elems[i] = elem
+		}
+
+		return elems, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported value type %s", v.Type())
+	}
+}
+
+// toJSONB converts value to a jsonb document the way go-pg encodes it (with encoding/json).
+func toJSONB(value interface{}) (jsonbValue, error) {
+	var b []byte
+
+	switch v := value.(type) {
+	case json.RawMessage:
+		b = v
+	case []byte:
+		b = v
+	default:
+		var err error
+
+		b, err = json.Marshal(value)
+		if err != nil {
+			return jsonbValue{}, errors.Wrapf(err, "encoding %T as json", value)
+		}
+	}
+
+	var doc interface{}
+
+	err := json.Unmarshal(b, &doc)
+	if err != nil {
+		return jsonbValue{}, errors.Wrap(err, "decoding json")
+	}
+
+	return jsonbValue{v: doc}, nil
+}
+
+// compareOp compares two non-NULL values. value is converted to the type of column.
+func compareOp(op Op, column interface{}, value interface{}) (sqlBool, error) {
+	c, err := compareValues(column, value)
+	if err != nil {
+		return sqlFalse, err
+	}
+
+	if c == compareUnknown {
+		return sqlUnknown, nil
+	}
+
+	switch op {
+	case OpEqual:
+		return toSQLBool(c == 0), nil
+	case OpNotEqual:
+		return toSQLBool(c != 0), nil
+	case OpGt:
+		return toSQLBool(c > 0), nil
+	case OpLt:
+		return toSQLBool(c < 0), nil
+	case OpGte:
+		return toSQLBool(c >= 0), nil
+	default:
+		return toSQLBool(c <= 0), nil
+	}
+}
+
+// compareUnknown is returned by compareValues when the result is NULL, e.g., when comparing arrays with NULL elements.
+const compareUnknown = math.MinInt32
+
+// compareValues returns -1, 0 or 1 if a is less than, equal to or greater than b. b is converted to the type of a.
+func compareValues(a interface{}, b interface{}) (int, error) {
+	mismatch := func() (int, error) {
+		return 0, fmt.Errorf("can't compare %s with %s", describeValue(a), describeValue(b))
+	}
+
+	switch x := a.(type) {
+	case int64, float64:
+		if s, ok := b.(string); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid input syntax for type numeric: %q", s)
+			}
+
+			b = n
+		}
+
+		switch y := b.(type) {
+		case int64:
+			if xi, ok := x.(int64); ok {
+				return compareInts(xi, y), nil
+			}
+
+			return compareFloats(toFloat(x), float64(y)), nil
+
+		case float64:
+			return compareFloats(toFloat(x), y), nil
+
+		default:
+			return mismatch()
+		}
+
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return mismatch()
+		}
+
+		return strings.Compare(x, y), nil
+
+	case bool:
+		y, ok := b.(bool)
+		if s, isString := b.(string); isString {
+			var err error
+
+			y, err = parseSQLBool(s)
+			if err != nil {
+				return 0, err
+			}
+
+			ok = true
+		}
+
+		if !ok {
+			return mismatch()
+		}
+
+		return compareInts(boolToInt(x), boolToInt(y)), nil
+
+	case time.Time:
+		y, ok := b.(time.Time)
+		if s, isString := b.(string); isString {
+			var err error
+
+			y, err = parseSQLTime(s)
+			if err != nil {
+				return 0, err
+			}
+
+			ok = true
+		}
+
+		if !ok {
+			return mismatch()
+		}
+
+		if x.Before(y) {
+			return -1, nil
+		} else if x.After(y) {
+			return 1, nil
+		}
+
+		return 0, nil
+
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if x[i] == nil || y[i] == nil {
+				return compareUnknown, nil
+			}
+
+			c, err := compareValues(x[i], y[i])
+			if err != nil || c != 0 {
+				return c, err
+			}
+		}
+
+		return compareInts(int64(len(x)), int64(len(y))), nil
+
+	case jsonbValue:
+		var y interface{}
+
+		switch v := b.(type) {
+		case jsonbValue:
+			y = v.v
+		case string:
+			doc, err := toJSONB(json.RawMessage(v))
+			if err != nil {
+				return 0, err
+			}
+
+			y = doc.v
+		default:
+			return mismatch()
+		}
+
+		if !reflect.DeepEqual(x.v, y) {
+			// jsonb has a total order, but only equality is supported in memory.
+			return 0, fmt.Errorf("jsonb values can only be compared for equality in memory")
+		}
+
+		return 0, nil
+
+	default:
+		return mismatch()
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+
+	return v.(float64)
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+// compareFloats orders NaN after every other number, like Postgres.
+func compareFloats(a, b float64) int {
+	switch {
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a):
+		return 1
+	case math.IsNaN(b):
+		return -1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// parseSQLBool parses the boolean literals Postgres accepts.
+func parseSQLBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "t", "true", "y", "yes", "on", "1":
+		return true, nil
+	case "f", "false", "n", "no", "off", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid input syntax for type boolean: %q", s)
+	}
+}
+
+// parseSQLTime parses the time formats go-pg renders and the common ISO 8601 forms. Times without a zone are UTC.
+func parseSQLTime(s string) (time.Time, error) {
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999-07:00:00",
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999-07",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02",
+	} {
+		t, err := time.Parse(layout, strings.TrimSpace(s))
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid input syntax for type timestamp: %q", s)
+}
+
+// arrayContains returns whether every element of b is an element of a (a @> b).
+func arrayContains(a interface{}, b interface{}) (sqlBool, error) {
+	x, ok := a.([]interface{})
+	if !ok {
+		return sqlFalse, fmt.Errorf("array containment requires arrays, got %s", describeValue(a))
+	}
+
+	y, ok := b.([]interface{})
+	if !ok {
+		return sqlFalse, fmt.Errorf("array containment requires arrays, got %s", describeValue(b))
+	}
+
+	for _, elem := range y {
+		found, err := arrayHas(x, elem)
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		if !found {
+			return sqlFalse, nil
+		}
+	}
+
+	return sqlTrue, nil
+}
+
+// arrayOverlaps returns whether a and b have an element in common (a && b).
+func arrayOverlaps(a interface{}, b interface{}) (sqlBool, error) {
+	x, ok := a.([]interface{})
+	if !ok {
+		return sqlFalse, fmt.Errorf("array overlap requires arrays, got %s", describeValue(a))
+	}
+
+	y, ok := b.([]interface{})
+	if !ok {
+		return sqlFalse, fmt.Errorf("array overlap requires arrays, got %s", describeValue(b))
+	}
+
+	for _, elem := range y {
+		found, err := arrayHas(x, elem)
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		if found {
+			return sqlTrue, nil
+		}
+	}
+
+	return sqlFalse, nil
+}
+
+// arrayHas returns whether elem equals an element of array. Like Postgres' containment operators, NULL equals nothing.
+func arrayHas(array []interface{}, elem interface{}) (bool, error) {
+	if elem == nil {
+		return false, nil
+	}
+
+	for _, e := range array {
+		if e == nil {
+			continue
+		}
+
+		c, err := compareValues(e, elem)
+		if err != nil {
+			return false, err
+		}
+
+		if c == 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// jsonbContains implements jsonb's @>. At the top level, an array contains a scalar that is one of its elements.
+func jsonbContains(a interface{}, b interface{}, top bool) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for key, yv := range y {
+			xv, ok := x[key]
+			if !ok || !jsonbContains(xv, yv, false) {
+				return false
+			}
+		}
+
+		return true
+
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			if top && isJSONScalar(b) {
+				y = []interface{}{b}
+			} else {
+				return false
+			}
+		}
+
+		for _, yv := range y {
+			found := false
+
+			for _, xv := range x {
+				// Elements must be of the same kind; a nested array contains a scalar only at the top level.
+				if jsonbContains(xv, yv, false) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return isJSONScalar(b) && reflect.DeepEqual(a, b)
+	}
+}
+
+func isJSONScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+// jsonbHasKey implements jsonb's ?: key is a top level key of an object or a string element of an array.
+func jsonbHasKey(doc interface{}, key string) bool {
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		_, ok := d[key]
+		return ok
+
+	case []interface{}:
+		for _, elem := range d {
+			if s, ok := elem.(string); ok && s == key {
+				return true
+			}
+		}
+
+		return false
+
+	case string:
+		return d == key
+
+	default:
+		return false
+	}
+}
+
+func describeValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case int64, float64:
+		return fmt.Sprintf("number %v", x)
+	case string:
+		return fmt.Sprintf("text %q", x)
+	case bool:
+		return fmt.Sprintf("boolean %t", x)
+	case time.Time:
+		return fmt.Sprintf("timestamp %s", x.Format(time.RFC3339Nano))
+	case []interface{}:
+		return "array"
+	case jsonbValue:
+		b, _ := json.Marshal(x.v)
+		return fmt.Sprintf("jsonb %s", bytes.TrimSpace(b))
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,317 @@
+package milo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// evalMatches evaluates a full-text search in memory. Only the simple configuration is supported: it lowercases words
+// without stemming or stop words, so the result matches Postgres' to_tsvector('simple', ...) for plain words.
+func (ev *evaluator) evalMatches(e Expression) (sqlBool, error) {
+	c, ok := e.column.(TSVectorColumn)
+	if !ok {
+		return sqlFalse, fmt.Errorf("@@ requires a full-text search column, got %T", e.column)
+	}
+
+	if c.config != "simple" {
+		return sqlFalse, fmt.Errorf("full-text search with configuration %q can't be evaluated in memory, only \"simple\" can", c.config)
+	}
+
+	query, ok := e.value.(string)
+	if !ok {
+		return sqlFalse, fmt.Errorf("full-text search query must be a string, got %T", e.value)
+	}
+
+	var doc tsDocument
+
+	if c.stored {
+		if len(c.columns) != 1 {
+			return sqlFalse, fmt.Errorf("a stored tsvector must have exactly one column")
+		}
+
+		name, ok := c.columns[0].(string)
+		if col, isColumn := c.columns[0].(Column); isColumn {
+			name, ok = string(col), true
+		}
+
+		if !ok {
+			return sqlFalse, fmt.Errorf("a stored tsvector must be a column name, got %T", c.columns[0])
+		}
+
+		_, v, err := ev.fieldValue(name)
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		value, err := normalizeValue(v)
+		if err != nil {
+			return sqlFalse, err
+		}
+
+		if value == nil {
+			return sqlUnknown, nil
+		}
+
+		s, ok := value.(string)
+		if !ok {
+			return sqlFalse, fmt.Errorf("stored tsvector column %q must be text, got %s", name, describeValue(value))
+		}
+
+		doc, err = parseTSVector(s)
+		if err != nil {
+			return sqlFalse, err
+		}
+	} else {
+		// coalesce(column::text, '') || ' ' || ...
+		texts := make([]string, len(c.columns))
+
+		for i, column := range c.columns {
+			value, err := ev.evalColumn(column)
+			if err != nil {
+				return sqlFalse, err
+			}
+
+			if value == nil {
+				continue
+			}
+
+			s, ok := value.(string)
+			if !ok {
+				s = fmt.Sprint(value)
+			}
+
+			texts[i] = s
+		}
+
+		doc = newTSDocument(tsWords(strings.Join(texts, " ")))
+	}
+
+	return toSQLBool(parseWebSearchQuery(query).matches(doc)), nil
+}
+
+// tsWords splits text into lowercase words of letters and digits.
+func tsWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tsDocument maps each lexeme to its positions.
+type tsDocument map[string][]int
+
+func newTSDocument(words []string) tsDocument {
+	doc := tsDocument{}
+
+	for i, word := range words {
+		doc[word] = append(doc[word], i+1)
+	}
+
+	return doc
+}
+
+// parseTSVector parses the text form of a tsvector, e.g. 'doe':2 'jane':1.
+func parseTSVector(s string) (tsDocument, error) {
+	doc := tsDocument{}
+
+	for i := 0; i < len(s); {
+		if s[i] == ' ' {
+			i++
+			continue
+		}
+
+		var lexeme strings.Builder
+
+		if s[i] == '\'' {
+			i++
+
+			for {
+				if i >= len(s) {
+					return nil, fmt.Errorf("invalid tsvector %q", s)
+				}
+
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						lexeme.WriteByte('\'')
+						i += 2
+
+						continue
+					}
+
+					i++
+
+					break
+				}
+
+				lexeme.WriteByte(s[i])
+				i++
+			}
+		} else {
+			for i < len(s) && s[i] != ' ' && s[i] != ':' {
+				lexeme.WriteByte(s[i])
+				i++
+			}
+		}
+
+		var positions []int
+
+		if i < len(s) && s[i] == ':' {
+			i++
+
+			start := i
+			for i < len(s) && s[i] != ' ' {
+				i++
+			}
+
+			for _, p := range strings.Split(s[start:i], ",") {
+				// Positions may have a weight (A-D).
+				n, err := strconv.Atoi(strings.TrimRight(p, "ABCD"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid tsvector %q", s)
+				}
+
+				positions = append(positions, n)
+			}
+		}
+
+		sort.Ints(positions)
+		doc[lexeme.String()] = append(doc[lexeme.String()], positions...)
+	}
+
+	return doc, nil
+}
+
+// tsQuery is a websearch_to_tsquery query: a disjunction of conjunctions of (possibly negated) phrases.
+type tsQuery [][]tsTerm
+
+type tsTerm struct {
+	words []string
+	not   bool
+}
+
+// parseWebSearchQuery parses query like websearch_to_tsquery: quoted text is a phrase, or separates alternatives and a
+// leading - negates a word or phrase. Everything else is joined with and.
+func parseWebSearchQuery(query string) tsQuery {
+	var q tsQuery
+	var group []tsTerm
+
+	for i := 0; i < len(query); {
+		if unicode.IsSpace(rune(query[i])) {
+			i++
+			continue
+		}
+
+		not := false
+		if query[i] == '-' {
+			not = true
+			i++
+		}
+
+		var text string
+
+		if i < len(query) && query[i] == '"' {
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				text = query[i+1:]
+				i = len(query)
+			} else {
+				text = query[i+1 : i+1+end]
+				i += end + 2
+			}
+		} else {
+			start := i
+			for i < len(query) && !unicode.IsSpace(rune(query[i])) && query[i] != '"' {
+				i++
+			}
+
+			text = query[start:i]
+
+			if !not && strings.EqualFold(text, "or") {
+				if len(group) > 0 {
+					q = append(q, group)
+					group = nil
+				}
+
+				continue
+			}
+		}
+
+		words := tsWords(text)
+		if len(words) == 0 {
+			continue
+		}
+
+		// The words of a quoted phrase or of words joined by punctuation (e.g., jane.doe) must be next to each other.
+		group = append(group, tsTerm{words: words, not: not})
+	}
+
+	if len(group) > 0 {
+		q = append(q, group)
+	}
+
+	return q
+}
+
+func (q tsQuery) matches(doc tsDocument) bool {
+	for _, group := range q {
+		matched := true
+
+		for _, term := range group {
+			if doc.hasPhrase(term.words) == term.not {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasPhrase returns whether words appear in doc at consecutive positions.
+func (doc tsDocument) hasPhrase(words []string) bool {
+	for _, start := range doc[words[0]] {
+		found := true
+
+		for i, word := range words[1:] {
+			if !containsInt(doc[word], start+i+1) {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return true
+		}
+	}
+
+	// A stored tsvector may not have positions, in which case any occurrence of every word matches.
+	if len(words) > 0 && len(doc[words[0]]) == 0 {
+		if _, ok := doc[words[0]]; ok {
+			for _, word := range words[1:] {
+				if _, ok := doc[word]; !ok {
+					return false
+				}
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
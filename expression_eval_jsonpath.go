@@ -0,0 +1,775 @@
+package milo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// evalJSONPath evaluates a jsonpath expression against a decoded jsonb document in lax mode and returns the items it
+// selects. It supports accessors (.key, ."key", .*, [n], [last], [*]) and filters (? (...)) with comparisons
+// (== != <> < <= > >=), starts with, exists, &&, || and !.
+func evalJSONPath(path string, doc interface{}) ([]interface{}, error) {
+	p := &jsonPathParser{
+		path: path,
+	}
+
+	err := p.tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekIdent("strict") {
+		return nil, fmt.Errorf("jsonpath %q: strict mode can't be evaluated in memory", path)
+	}
+
+	if p.peekIdent("lax") {
+		p.pos++
+	}
+
+	expr, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	return expr.eval(doc, doc), nil
+}
+
+type jsonPathTokenKind int
+
+const (
+	jsonPathPunct jsonPathTokenKind = iota
+	jsonPathIdent
+	jsonPathString
+	jsonPathNumber
+)
+
+type jsonPathToken struct {
+	kind jsonPathTokenKind
+	text string
+	// value is the value of a string or number token.
+	value interface{}
+}
+
+type jsonPathParser struct {
+	path   string
+	tokens []jsonPathToken
+	pos    int
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("jsonpath %q: %s", p.path, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonPathParser) tokenize() error {
+	s := p.path
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(s) {
+				return p.errorf("unterminated string")
+			}
+
+			var str string
+
+			err := json.Unmarshal([]byte(s[i:end+1]), &str)
+			if err != nil {
+				return p.errorf("invalid string %s", s[i:end+1])
+			}
+
+			p.tokens = append(p.tokens, jsonPathToken{kind: jsonPathString, text: s[i : end+1], value: str})
+			i = end + 1
+
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			end := i + 1
+			for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.' || s[end] == 'e' || s[end] == 'E') {
+				end++
+			}
+
+			n, err := strconv.ParseFloat(s[i:end], 64)
+			if err != nil {
+				return p.errorf("invalid number %s", s[i:end])
+			}
+
+			p.tokens = append(p.tokens, jsonPathToken{kind: jsonPathNumber, text: s[i:end], value: n})
+			i = end
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i + 1
+			for end < len(s) && (s[end] == '_' || unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end]))) {
+				end++
+			}
+
+			p.tokens = append(p.tokens, jsonPathToken{kind: jsonPathIdent, text: s[i:end]})
+			i = end
+
+		default:
+			text := s[i : i+1]
+
+			if i+1 < len(s) {
+				switch s[i : i+2] {
+				case "==", "!=", "<>", "<=", ">=", "&&", "||":
+					text = s[i : i+2]
+				}
+			}
+
+			if len(text) == 1 && !strings.Contains("$@.*[],?()<>!", text) {
+				return p.errorf("unexpected %q", text)
+			}
+
+			p.tokens = append(p.tokens, jsonPathToken{kind: jsonPathPunct, text: text})
+			i += len(text)
+		}
+	}
+
+	return nil
+}
+
+func (p *jsonPathParser) peek(kind jsonPathTokenKind, text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind && p.tokens[p.pos].text == text
+}
+
+func (p *jsonPathParser) peekPunct(text string) bool {
+	return p.peek(jsonPathPunct, text)
+}
+
+func (p *jsonPathParser) peekIdent(text string) bool {
+	return p.peek(jsonPathIdent, text)
+}
+
+func (p *jsonPathParser) expect(text string) error {
+	if !p.peekPunct(text) {
+		if p.pos < len(p.tokens) {
+			return p.errorf("expected %q, got %q", text, p.tokens[p.pos].text)
+		}
+
+		return p.errorf("expected %q at end", text)
+	}
+
+	p.pos++
+
+	return nil
+}
+
+// jsonPathExpr is a path starting at the document ($) or the current filter item (@).
+type jsonPathExpr struct {
+	current   bool
+	accessors []jsonPathAccessor
+}
+
+type jsonPathAccessor struct {
+	key      string
+	wildcard bool
+	// member is true for .key and .*, false for [...].
+	member  bool
+	indexes []jsonPathIndex
+	filter  jsonPathPredicate
+}
+
+type jsonPathIndex struct {
+	n    int
+	last bool
+}
+
+func (p *jsonPathParser) parsePath() (*jsonPathExpr, error) {
+	expr := &jsonPathExpr{}
+
+	switch {
+	case p.peekPunct("$"):
+	case p.peekPunct("@"):
+		expr.current = true
+	default:
+		return nil, p.errorf("path must start with $ or @")
+	}
+
+	p.pos++
+
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.peekPunct("."):
+			p.pos++
+
+			if p.pos >= len(p.tokens) {
+				return nil, p.errorf("expected a key after .")
+			}
+
+			t := p.tokens[p.pos]
+
+			switch {
+			case t.kind == jsonPathPunct && t.text == "*":
+				expr.accessors = append(expr.accessors, jsonPathAccessor{member: true, wildcard: true})
+			case t.kind == jsonPathIdent:
+				expr.accessors = append(expr.accessors, jsonPathAccessor{member: true, key: t.text})
+			case t.kind == jsonPathString:
+				expr.accessors = append(expr.accessors, jsonPathAccessor{member: true, key: t.value.(string)})
+			default:
+				return nil, p.errorf("expected a key after ., got %q", t.text)
+			}
+
+			p.pos++
+
+		case p.peekPunct("["):
+			p.pos++
+
+			if p.peekPunct("*") {
+				p.pos++
+				expr.accessors = append(expr.accessors, jsonPathAccessor{wildcard: true})
+			} else {
+				var indexes []jsonPathIndex
+
+				for {
+					switch {
+					case p.peekIdent("last"):
+						indexes = append(indexes, jsonPathIndex{last: true})
+					case p.pos < len(p.tokens) && p.tokens[p.pos].kind == jsonPathNumber:
+						n := p.tokens[p.pos].value.(float64)
+						if n != float64(int(n)) {
+							return nil, p.errorf("array index %v isn't an integer", n)
+						}
+
+						indexes = append(indexes, jsonPathIndex{n: int(n)})
+					default:
+						return nil, p.errorf("expected an array index")
+					}
+
+					p.pos++
+
+					if !p.peekPunct(",") {
+						break
+					}
+
+					p.pos++
+				}
+
+				expr.accessors = append(expr.accessors, jsonPathAccessor{indexes: indexes})
+			}
+
+			err := p.expect("]")
+			if err != nil {
+				return nil, err
+			}
+
+		case p.peekPunct("?"):
+			p.pos++
+
+			err := p.expect("(")
+			if err != nil {
+				return nil, err
+			}
+
+			pred, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			err = p.expect(")")
+			if err != nil {
+				return nil, err
+			}
+
+			expr.accessors = append(expr.accessors, jsonPathAccessor{filter: pred})
+
+		default:
+			return expr, nil
+		}
+	}
+
+	return expr, nil
+}
+
+// eval returns the items selected by the path. root is the document ($) and current is the filter item (@).
+func (expr *jsonPathExpr) eval(root interface{}, current interface{}) []interface{} {
+	items := []interface{}{root}
+	if expr.current {
+		items = []interface{}{current}
+	}
+
+	for _, a := range expr.accessors {
+		var next []interface{}
+
+		for _, item := range items {
+			next = append(next, a.apply(root, item)...)
+		}
+
+		items = next
+	}
+
+	return items
+}
+
+// apply applies the accessor to item in lax mode: member accessors and filters unwrap arrays, array accessors wrap
+// other values in an array and missing keys and indexes select nothing.
+func (a jsonPathAccessor) apply(root interface{}, item interface{}) []interface{} {
+	if a.member || a.filter != nil {
+		if array, ok := item.([]interface{}); ok {
+			var items []interface{}
+
+			for _, elem := range array {
+				items = append(items, a.applyOne(root, elem)...)
+			}
+
+			return items
+		}
+	}
+
+	return a.applyOne(root, item)
+}
+
+func (a jsonPathAccessor) applyOne(root interface{}, item interface{}) []interface{} {
+	switch {
+	case a.filter != nil:
+		if a.filter.eval(root, item) == sqlTrue {
+			return []interface{}{item}
+		}
+
+		return nil
+
+	case a.member:
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		if !a.wildcard {
+			value, ok := obj[a.key]
+			if !ok {
+				return nil
+			}
+
+			return []interface{}{value}
+		}
+
+		keys := sortedKeys(obj)
+
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = obj[key]
+		}
+
+		return values
+
+	default:
+		array, ok := item.([]interface{})
+		if !ok {
+			array = []interface{}{item}
+		}
+
+		if a.wildcard {
+			return array
+		}
+
+		var items []interface{}
+
+		for _, index := range a.indexes {
+			i := index.n
+			if index.last {
+				i = len(array) - 1
+			}
+
+			if i >= 0 && i < len(array) {
+				items = append(items, array[i])
+			}
+		}
+
+		return items
+	}
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+
+	// Postgres orders jsonb keys by length, then byte-wise.
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && (len(keys[j]) < len(keys[j-1]) || len(keys[j]) == len(keys[j-1]) && keys[j] < keys[j-1]); j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+
+	return keys
+}
+
+// jsonPathPredicate is a filter condition. Like in SQL, it is true, false or unknown.
+type jsonPathPredicate interface {
+	eval(root interface{}, current interface{}) sqlBool
+}
+
+type jsonPathAnd struct {
+	left, right jsonPathPredicate
+}
+
+func (pred jsonPathAnd) eval(root interface{}, current interface{}) sqlBool {
+	l := pred.left.eval(root, current)
+	if l == sqlFalse {
+		return sqlFalse
+	}
+
+	r := pred.right.eval(root, current)
+	if r == sqlFalse {
+		return sqlFalse
+	}
+
+	if l == sqlUnknown || r == sqlUnknown {
+		return sqlUnknown
+	}
+
+	return sqlTrue
+}
+
+type jsonPathOr struct {
+	left, right jsonPathPredicate
+}
+
+func (pred jsonPathOr) eval(root interface{}, current interface{}) sqlBool {
+	l := pred.left.eval(root, current)
+	if l == sqlTrue {
+		return sqlTrue
+	}
+
+	r := pred.right.eval(root, current)
+	if r == sqlTrue {
+		return sqlTrue
+	}
+
+	if l == sqlUnknown || r == sqlUnknown {
+		return sqlUnknown
+	}
+
+	return sqlFalse
+}
+
+type jsonPathNot struct {
+	pred jsonPathPredicate
+}
+
+func (pred jsonPathNot) eval(root interface{}, current interface{}) sqlBool {
+	switch pred.pred.eval(root, current) {
+	case sqlTrue:
+		return sqlFalse
+	case sqlFalse:
+		return sqlTrue
+	default:
+		return sqlUnknown
+	}
+}
+
+type jsonPathExists struct {
+	path *jsonPathExpr
+}
+
+func (pred jsonPathExists) eval(root interface{}, current interface{}) sqlBool {
+	return toSQLBool(len(pred.path.eval(root, current)) > 0)
+}
+
+// jsonPathOperand is a path or a literal on one side of a comparison.
+type jsonPathOperand struct {
+	path    *jsonPathExpr
+	literal interface{}
+}
+
+func (o jsonPathOperand) eval(root interface{}, current interface{}) []interface{} {
+	if o.path == nil {
+		return []interface{}{o.literal}
+	}
+
+	// In lax mode, arrays are unwrapped in comparisons.
+	var items []interface{}
+
+	for _, item := range o.path.eval(root, current) {
+		if array, ok := item.([]interface{}); ok {
+			items = append(items, array...)
+		} else {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+type jsonPathComparison struct {
+	op          string
+	left, right jsonPathOperand
+}
+
+// eval is true if any pair of items from both sides compares true. In lax mode, it is unknown if none does and a pair
+// can't be compared (e.g., a string and a number).
+func (pred jsonPathComparison) eval(root interface{}, current interface{}) sqlBool {
+	result := sqlFalse
+
+	for _, l := range pred.left.eval(root, current) {
+		for _, r := range pred.right.eval(root, current) {
+			switch compareJSONPathItems(pred.op, l, r) {
+			case sqlTrue:
+				return sqlTrue
+			case sqlUnknown:
+				result = sqlUnknown
+			}
+		}
+	}
+
+	return result
+}
+
+func compareJSONPathItems(op string, a interface{}, b interface{}) sqlBool {
+	if a == nil || b == nil {
+		// null equals only null. Other comparisons with null are false, not unknown.
+		switch op {
+		case "==":
+			return toSQLBool(a == nil && b == nil)
+		case "!=", "<>":
+			return toSQLBool(a != nil || b != nil)
+		default:
+			return sqlFalse
+		}
+	}
+
+	var c int
+
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return sqlUnknown
+		}
+
+		c = compareFloats(x, y)
+
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return sqlUnknown
+		}
+
+		c = strings.Compare(x, y)
+
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return sqlUnknown
+		}
+
+		c = int(boolToInt(x) - boolToInt(y))
+
+	default:
+		// Objects and arrays can't be compared.
+		return sqlUnknown
+	}
+
+	switch op {
+	case "==":
+		return toSQLBool(c == 0)
+	case "!=", "<>":
+		return toSQLBool(c != 0)
+	case "<":
+		return toSQLBool(c < 0)
+	case "<=":
+		return toSQLBool(c <= 0)
+	case ">":
+		return toSQLBool(c > 0)
+	default:
+		return toSQLBool(c >= 0)
+	}
+}
+
+type jsonPathStartsWith struct {
+	operand jsonPathOperand
+	prefix  string
+}
+
+func (pred jsonPathStartsWith) eval(root interface{}, current interface{}) sqlBool {
+	result := sqlFalse
+
+	for _, item := range pred.operand.eval(root, current) {
+		s, ok := item.(string)
+		if !ok {
+			result = sqlUnknown
+			continue
+		}
+
+		if strings.HasPrefix(s, pred.prefix) {
+			return sqlTrue
+		}
+	}
+
+	return result
+}
+
+func (p *jsonPathParser) parseOr() (jsonPathPredicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekPunct("||") {
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = jsonPathOr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *jsonPathParser) parseAnd() (jsonPathPredicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekPunct("&&") {
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = jsonPathAnd{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *jsonPathParser) parseUnary() (jsonPathPredicate, error) {
+	switch {
+	case p.peekPunct("!"):
+		p.pos++
+
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonPathNot{pred: pred}, nil
+
+	case p.peekPunct("("):
+		p.pos++
+
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		return pred, p.expect(")")
+
+	case p.peekIdent("exists"):
+		p.pos++
+
+		err := p.expect("(")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonPathExists{path: path}, p.expect(")")
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekIdent("starts") {
+		p.pos++
+
+		if !p.peekIdent("with") {
+			return nil, p.errorf("expected with after starts")
+		}
+
+		p.pos++
+
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != jsonPathString {
+			return nil, p.errorf("starts with requires a string")
+		}
+
+		prefix := p.tokens[p.pos].value.(string)
+		p.pos++
+
+		return jsonPathStartsWith{operand: left, prefix: prefix}, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("expected a comparison")
+	}
+
+	op := p.tokens[p.pos].text
+
+	switch op {
+	case "==", "!=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, p.errorf("expected a comparison, got %q", op)
+	}
+
+	p.pos++
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonPathComparison{op: op, left: left, right: right}, nil
+}
+
+func (p *jsonPathParser) parseOperand() (jsonPathOperand, error) {
+	if p.pos >= len(p.tokens) {
+		return jsonPathOperand{}, p.errorf("expected a value")
+	}
+
+	t := p.tokens[p.pos]
+
+	switch {
+	case p.peekPunct("$") || p.peekPunct("@"):
+		path, err := p.parsePath()
+		if err != nil {
+			return jsonPathOperand{}, err
+		}
+
+		return jsonPathOperand{path: path}, nil
+
+	case t.kind == jsonPathString || t.kind == jsonPathNumber:
+		p.pos++
+		return jsonPathOperand{literal: t.value}, nil
+
+	case t.kind == jsonPathIdent && (t.text == "true" || t.text == "false"):
+		p.pos++
+		return jsonPathOperand{literal: t.text == "true"}, nil
+
+	case t.kind == jsonPathIdent && t.text == "null":
+		p.pos++
+		return jsonPathOperand{literal: nil}, nil
+
+	default:
+		return jsonPathOperand{}, p.errorf("expected a value, got %q", t.text)
+	}
+}
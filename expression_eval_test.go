@@ -0,0 +1,441 @@
+package milo
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type evalModel struct {
+	tableName struct{} `pg:"eval_models"`
+
+	ID string `pg:"id"`
+
+	Name     string  `pg:"name"`
+	Nickname *string `pg:"nickname"`
+	Age      int     `pg:"age"`
+	Visits   int     `pg:"visits,use_zero"`
+	Score    float64 `pg:"score"`
+	Verified bool    `pg:"verified"`
+
+	Answers map[string]interface{} `pg:"answers,type:jsonb"`
+	Tags    []string               `pg:"tags,array"`
+	Codes   []int                  `pg:"codes,array"`
+	Search  string                 `pg:"search,type:tsvector"`
+
+	CreatedAt time.Time `pg:"created_at"`
+}
+
+var _ Model = (*evalModel)(nil)
+
+// evalModel is its own entity.
+func (e *evalModel) FromEntity(entity interface{}) error {
+	*e = *entity.(*evalModel)
+
+	return nil
+}
+
+func (e *evalModel) ToEntity() (interface{}, error) {
+	entity := *e
+
+	return &entity, nil
+}
+
+func evalModels() []*evalModel {
+	nickname := "Janie"
+	empty := ""
+
+	return []*evalModel{
+		{
+			ID:       "jane",
+			Name:     "Jane Doe",
+			Nickname: &nickname,
+			Age:      34,
+			Visits:   3,
+			Score:    4.5,
+			Verified: true,
+			Answers: map[string]interface{}{
+				"smoker": false,
+				"height": 170,
+				"medications": []interface{}{
+					map[string]interface{}{"name": "aspirin", "dose": 100},
+					map[string]interface{}{"name": "ibuprofen", "dose": 200},
+				},
+				"address": map[string]interface{}{"city": "Boston"},
+			},
+			Tags:      []string{"vip", "new"},
+			Codes:     []int{1, 2, 3},
+			Search:    "'doe':2 'jane':1",
+			CreatedAt: time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC),
+		},
+		{
+			ID:       "john",
+			Name:     "John Smith",
+			Nickname: &empty,
+			Age:      51,
+			Score:    2,
+			Answers: map[string]interface{}{
+				"smoker": true,
+				"height": 182.5,
+				"medications": []interface{}{
+					map[string]interface{}{"name": "metformin", "dose": 500},
+				},
+			},
+			Tags:      []string{"new"},
+			Codes:     []int{4},
+			Search:    "'john':1 'smith':2",
+			CreatedAt: time.Date(2021, 11, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			// Zero values are NULL except for visits.
+			ID: "empty",
+		},
+	}
+}
+
+func TestExpression_Matches(t *testing.T) {
+	models := evalModels()
+	jane, john, empty := models[0], models[1], models[2]
+
+	tests := []struct {
+		name     string
+		model    *evalModel
+		expr     Expression
+		expected bool
+	}{
+		{name: "equal", model: jane, expr: Equal("name", "Jane Doe"), expected: true},
+		{name: "equal column", model: jane, expr: Equal(Column("name"), "Jane Doe"), expected: true},
+		{name: "equal case sensitive", model: jane, expr: Equal("name", "jane doe"), expected: false},
+		{name: "not equal", model: jane, expr: NotEqual("name", "John Smith"), expected: true},
+		{name: "gt int", model: john, expr: Gt("age", 50), expected: true},
+		{name: "gt int false", model: jane, expr: Gt("age", 50), expected: false},
+		{name: "lt float", model: john, expr: Lt("score", 2.5), expected: true},
+		{name: "gte", model: john, expr: Gte("score", 2), expected: true},
+		{name: "lte", model: jane, expr: Lte("age", 34), expected: true},
+		{name: "int and float", model: jane, expr: Equal("score", 4.5), expected: true},
+		{name: "numeric string", model: jane, expr: Equal("age", "34"), expected: true},
+		{name: "string compared byte-wise", model: jane, expr: Lt("name", "John"), expected: true},
+		{name: "bool", model: jane, expr: Equal("verified", true), expected: true},
+		{name: "bool string", model: jane, expr: Equal("verified", "t"), expected: true},
+		{name: "time", model: jane, expr: Gt("created_at", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)), expected: true},
+		{name: "time string", model: jane, expr: Lt("created_at", "2021-06-01"), expected: true},
+		{name: "pointer", model: jane, expr: Equal("nickname", "Janie"), expected: true},
+		{name: "empty string pointer is not null", model: john, expr: IsNotNull("nickname"), expected: true},
+
+		{name: "zero value is null", model: empty, expr: IsNull("age"), expected: true},
+		{name: "zero value is null for false", model: john, expr: IsNull("verified"), expected: true},
+		{name: "use_zero is not null", model: empty, expr: Equal("visits", 0), expected: true},
+		{name: "nil pointer is null", model: empty, expr: IsNull("nickname"), expected: true},
+		{name: "null equal", model: empty, expr: Equal("age", 0), expected: false},
+		{name: "null not equal", model: empty, expr: NotEqual("age", 1), expected: false},
+		{name: "equal null", model: jane, expr: Equal("nickname", nil), expected: false},
+		{name: "null and false", model: empty, expr: And(Equal("age", 1), Equal("visits", 1)), expected: false},
+		{name: "null or true", model: empty, expr: Or(Equal("age", 1), Equal("visits", 0)), expected: true},
+		{name: "null or false", model: empty, expr: Or(Equal("age", 1), Equal("visits", 1)), expected: false},
+		{name: "empty and", model: empty, expr: And(), expected: true},
+		{name: "empty or", model: empty, expr: Or(), expected: false},
+
+		{name: "array contains", model: jane, expr: ArrayContains("tags", []string{"vip"}), expected: true},
+		{name: "array contains false", model: john, expr: ArrayContains("tags", []string{"vip"}), expected: false},
+		{name: "array contains empty", model: john, expr: ArrayContains("tags", []string{}), expected: true},
+		{name: "array contained by", model: john, expr: ArrayContainedBy("tags", []string{"new", "vip"}), expected: true},
+		{name: "array contained by false", model: jane, expr: ArrayContainedBy("tags", []string{"new"}), expected: false},
+		{name: "array overlaps", model: jane, expr: ArrayOverlaps("codes", []int{3, 4}), expected: true},
+		{name: "array overlaps false", model: john, expr: ArrayOverlaps("codes", []int{1, 2}), expected: false},
+		{name: "any equal", model: jane, expr: AnyEqual("codes", 2), expected: true},
+		{name: "any equal false", model: john, expr: AnyEqual("codes", 2), expected: false},
+		{name: "null array", model: empty, expr: AnyEqual("codes", 2), expected: false},
+
+		{name: "json contains", model: jane, expr: JSONContains("answers", map[string]interface{}{"smoker": false}), expected: true},
+		{name: "json contains nested", model: jane, expr: JSONContains("answers", map[string]interface{}{
+			"medications": []interface{}{map[string]interface{}{"name": "ibuprofen"}},
+		}), expected: true},
+		{name: "json contains false", model: john, expr: JSONContains("answers", map[string]interface{}{"smoker": false}), expected: false},
+		{name: "json has key", model: jane, expr: JSONHasKey("answers", "address"), expected: true},
+		{name: "json has key false", model: john, expr: JSONHasKey("answers", "address"), expected: false},
+		{name: "json has any keys", model: john, expr: JSONHasAnyKeys("answers", "address", "smoker"), expected: true},
+		{name: "json has all keys", model: john, expr: JSONHasAllKeys("answers", "address", "smoker"), expected: false},
+		{name: "json path text", model: jane, expr: Equal(JSONPath("answers", "address", "city"), "Boston"), expected: true},
+		{name: "json path index", model: jane, expr: Equal(JSONPath("answers", "medications", 1, "name"), "ibuprofen"), expected: true},
+		{name: "json path negative index", model: jane, expr: Equal(JSONPath("answers", "medications", -1, "name"), "ibuprofen"), expected: true},
+		{name: "json path number as text", model: john, expr: Equal(JSONPath("answers", "height"), "182.5"), expected: true},
+		{name: "json path missing is null", model: john, expr: IsNull(JSONPath("answers", "address", "city")), expected: true},
+		{name: "json path matches", model: jane, expr: JSONPathMatches("answers", `$.medications[*] ? (@.name == "aspirin")`), expected: true},
+		{name: "json path matches false", model: john, expr: JSONPathMatches("answers", `$.medications[*] ? (@.name == "aspirin")`), expected: false},
+		{name: "json path matches lax", model: jane, expr: JSONPathMatches("answers", `$.medications.name ? (@ starts with "ibu")`), expected: true},
+		{name: "json path matches and", model: jane, expr: JSONPathMatches("answers", `$ ? (@.height > 160 && !(@.smoker == true))`), expected: true},
+		{name: "json path matches type mismatch", model: jane, expr: JSONPathMatches("answers", `$ ? (@.height == "170")`), expected: false},
+		{name: "json path matches exists", model: jane, expr: JSONPathMatches("answers", `$ ? (exists(@.address.city))`), expected: true},
+		{name: "json path matches last", model: jane, expr: JSONPathMatches("answers", `$.medications[last] ? (@.dose >= 200)`), expected: true},
+		{name: "json path matches missing", model: john, expr: JSONPathMatches("answers", `$.address.city`), expected: false},
+
+		{name: "full-text", model: jane, expr: Matches([]interface{}{"name", "nickname"}, "jane", "simple"), expected: true},
+		{name: "full-text and", model: jane, expr: Matches([]interface{}{"name"}, "jane smith", "simple"), expected: false},
+		{name: "full-text or", model: jane, expr: Matches([]interface{}{"name"}, "jane or smith", "simple"), expected: true},
+		{name: "full-text phrase", model: jane, expr: Matches([]interface{}{"name"}, `"doe jane"`, "simple"), expected: false},
+		{name: "full-text negation", model: john, expr: Matches([]interface{}{"name"}, "john -doe", "simple"), expected: true},
+		{name: "full-text null column", model: empty, expr: Matches([]interface{}{"name", "nickname"}, "jane", "simple"), expected: false},
+		{name: "full-text stored", model: john, expr: MatchesVector("search", `"john smith"`, "simple"), expected: true},
+		{name: "full-text stored false", model: john, expr: MatchesVector("search", "jane", "simple"), expected: false},
+
+		{name: "lower", model: jane, expr: Equal(Lower("name"), "jane doe"), expected: true},
+		{name: "upper", model: jane, expr: Equal(Upper("name"), "JANE DOE"), expected: true},
+		{name: "coalesce", model: empty, expr: Equal(Coalesce("nickname", Literal("none")), "none"), expected: true},
+		{name: "date_trunc", model: jane, expr: Equal(DateTrunc("month", "created_at"), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)), expected: true},
+
+		{name: "fluent", model: jane, expr: Col("age").Gt(30).And(Col("tags").ArrayContains([]string{"vip"})), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			matched, err := tt.expr.Matches(tt.model)
+			assert.NoError(err)
+			assert.Equal(tt.expected, matched)
+
+			// A model value works like a pointer.
+			matched, err = tt.expr.Matches(*tt.model)
+			assert.NoError(err)
+			assert.Equal(tt.expected, matched)
+		})
+	}
+}
+
+func TestExpression_Matches_Errors(t *testing.T) {
+	jane := evalModels()[0]
+
+	tests := []struct {
+		name        string
+		model       interface{}
+		expr        Expression
+		expectedErr string
+	}{
+		{
+			name:        "not a struct",
+			model:       "jane",
+			expr:        Equal("name", "Jane Doe"),
+			expectedErr: "model must be a struct or a pointer to a struct, got string",
+		},
+		{
+			name:        "unknown column",
+			model:       jane,
+			expr:        Equal("nam", "Jane Doe"),
+			expectedErr: `unknown column "nam"`,
+		},
+		{
+			name:        "invalid number",
+			model:       jane,
+			expr:        Equal("age", "thirty"),
+			expectedErr: `invalid input syntax for type numeric: "thirty"`,
+		},
+		{
+			name:        "raw",
+			model:       jane,
+			expr:        Raw("name = ?", "Jane Doe"),
+			expectedErr: `raw expression "name = ?" can't be evaluated in memory`,
+		},
+		{
+			name:        "order",
+			model:       jane,
+			expr:        OrderBy("name"),
+			expectedErr: "can't be evaluated as a condition",
+		},
+		{
+			name:        "full-text config",
+			model:       jane,
+			expr:        Matches([]interface{}{"name"}, "jane", "english"),
+			expectedErr: `full-text search with configuration "english" can't be evaluated in memory`,
+		},
+		{
+			name:        "strict jsonpath",
+			model:       jane,
+			expr:        JSONPathMatches("answers", `strict $.address`),
+			expectedErr: "strict mode can't be evaluated in memory",
+		},
+		{
+			name:        "invalid jsonpath",
+			model:       jane,
+			expr:        JSONPathMatches("answers", `$.medications[`),
+			expectedErr: "expected an array index",
+		},
+		{
+			name:        "unsupported function",
+			model:       jane,
+			expr:        Equal(Fn("md5", "name"), "abc"),
+			expectedErr: "function md5 can't be evaluated in memory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := tt.expr.Matches(tt.model)
+			if assert.Error(err) {
+				assert.Contains(err.Error(), tt.expectedErr)
+			}
+		})
+	}
+}
+
+// TestStore_ExpressionMatches checks that Matches agrees with Postgres.
+func TestStore_ExpressionMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	defer db.Close()
+
+	err := db.Ping(context.Background())
+	assert.NoError(err)
+
+	_, err = db.Exec("SET TIME ZONE 'UTC'")
+	assert.NoError(err)
+
+	err = db.Model((*evalModel)(nil)).DropTable(&orm.DropTableOptions{
+		IfExists: true,
+	})
+	assert.NoError(err)
+
+	err = db.Model((*evalModel)(nil)).CreateTable(&orm.CreateTableOptions{})
+	assert.NoError(err)
+
+	store, err := NewStore(db, EntityModelMap{
+		reflect.TypeOf(&evalModel{}): reflect.TypeOf(&evalModel{}),
+	})
+	assert.NoError(err)
+
+	models := evalModels()
+	for _, model := range models {
+		model.ID = uuid.New().String()
+
+		err = store.Save(context.Background(), model)
+		assert.NoError(err)
+	}
+
+	exprs := []Expression{
+		Equal("name", "Jane Doe"),
+		NotEqual("name", "John Smith"),
+		Gt("age", 50),
+		Lt("name", "John"),
+		Equal("age", "34"),
+		Equal("verified", true),
+		Lt("created_at", "2021-06-01"),
+		IsNull("nickname"),
+		IsNotNull("nickname"),
+		Equal("visits", 0),
+		NotEqual("age", 1),
+		Or(Equal("age", 1), Equal("visits", 0)),
+		And(Equal("age", 1), Equal("visits", 1)),
+		ArrayContains("tags", []string{"vip"}),
+		ArrayContainedBy("tags", []string{"new"}),
+		ArrayOverlaps("codes", []int{3, 4}),
+		AnyEqual("codes", 2),
+		JSONContains("answers", map[string]interface{}{"medications": []interface{}{map[string]interface{}{"name": "ibuprofen"}}}),
+		JSONHasAnyKeys("answers", "address", "smoker"),
+		JSONHasAllKeys("answers", "address", "smoker"),
+		Equal(JSONPath("answers", "medications", -1, "name"), "ibuprofen"),
+		Equal(JSONPath("answers", "height"), "182.5"),
+		IsNull(JSONPath("answers", "address", "city")),
+		JSONPathMatches("answers", `$.medications.name ? (@ starts with "ibu")`),
+		JSONPathMatches("answers", `$ ? (@.height > 160 && !(@.smoker == true))`),
+		JSONPathMatches("answers", `$ ? (@.height == "170")`),
+		Matches([]interface{}{"name", "nickname"}, "jane or smith", "simple"),
+		Matches([]interface{}{"name"}, "john -doe", "simple"),
+		MatchesVector("search", `"john smith"`, "simple"),
+		Equal(Coalesce("nickname", Literal("none")), "none"),
+		Equal(DateTrunc("month", "created_at"), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	for _, expr := range exprs {
+		var found []*evalModel
+
+		err = store.FindBy(context.Background(), &found, expr)
+		if !assert.NoError(err, expr.String()) {
+			continue
+		}
+
+		var expected []string
+		for _, model := range found {
+			expected = append(expected, model.ID)
+		}
+
+		var actual []string
+
+		for _, model := range models {
+			matched, err := expr.Matches(model)
+			assert.NoError(err, expr.String())
+
+			if matched {
+				actual = append(actual, model.ID)
+			}
+		}
+
+		sort.Strings(expected)
+		sort.Strings(actual)
+
+		assert.Equal(expected, actual, expr.String())
+	}
+}
+
+func TestSelectModels(t *testing.T) {
+	assert := assert.New(t)
+
+	models := evalModels()
+	jane, john, empty := models[0], models[1], models[2]
+
+	ids := func(selected []interface{}) []string {
+		var ids []string
+		for _, model := range selected {
+			ids = append(ids, model.(*evalModel).ID)
+		}
+
+		return ids
+	}
+
+	all := []interface{}{jane, john, empty}
+
+	selected, err := SelectModels(all)
+	assert.NoError(err)
+	assert.Equal([]string{"jane", "john", "empty"}, ids(selected))
+
+	selected, err = SelectModels(all, OrderBy("age"))
+	assert.NoError(err)
+	assert.Equal([]string{"jane", "john", "empty"}, ids(selected), "NULLs are last in ascending order")
+
+	selected, err = SelectModels(all, OrderByDesc("age"))
+	assert.NoError(err)
+	assert.Equal([]string{"empty", "john", "jane"}, ids(selected), "NULLs are first in descending order")
+
+	selected, err = SelectModels(all, IsNotNull("name"), OrderByDesc("name"))
+	assert.NoError(err)
+	assert.Equal([]string{"john", "jane"}, ids(selected))
+
+	// Equal models keep their order.
+	selected, err = SelectModels(all, OrderBy("visits"), OrderBy(Lower("name")))
+	assert.NoError(err)
+	assert.Equal([]string{"john", "empty", "jane"}, ids(selected))
+
+	selected, err = SelectModels(all, Gt("age", 40), Lt("age", 60))
+	assert.NoError(err)
+	assert.Equal([]string{"john"}, ids(selected))
+
+	_, err = SelectModels(all, OrderBy("nam"))
+	assert.Error(err)
+
+	_, err = SelectModels(all, OrderBy("answers"))
+	assert.Error(err)
+}
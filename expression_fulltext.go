@@ -0,0 +1,154 @@
+package milo
+
+import "fmt"
+
+const (
+	OpMatches Op = "@@"
+)
+
+// TSVectorColumn is the document side of a full-text search. See Matches and MatchesVector.
+type TSVectorColumn struct {
+	columns []interface{}
+	config  string
+	stored  bool
+}
+
+func (c TSVectorColumn) Columns() []interface{} {
+	return c.columns
+}
+
+func (c TSVectorColumn) Config() string {
+	return c.config
+}
+
+// TSRankColumn is a column reference for the ts_rank of a full-text search. See Rank.
+type TSRankColumn struct {
+	match Expression
+}
+
+// Matches matches rows where the text of columns matches query. columns are concatenated (NULLs are treated as empty
+// strings) and converted with to_tsvector. query is parsed with websearch_to_tsquery, so it supports quoted phrases,
+// "or" and "-" for negation. config is the text search configuration (e.g., english). If config is empty, the
+// database's default_text_search_config is used.
+func Matches(columns []interface{}, query string, config string) Expression {
+	return Expression{
+		column: TSVectorColumn{
+			columns: columns,
+			config:  config,
+		},
+		op:    OpMatches,
+		value: query,
+	}
+}
+
+// MatchesVector is like Matches, but searches a stored tsvector column instead of converting text columns.
+func MatchesVector(column interface{}, query string, config string) Expression {
+	return Expression{
+		column: TSVectorColumn{
+			columns: []interface{}{column},
+			config:  config,
+			stored:  true,
+		},
+		op:    OpMatches,
+		value: query,
+	}
+}
+
+// Rank returns a column reference for the ts_rank of match, which must be created with Matches or MatchesVector. Pass
+// it to OrderByDesc (or use OrderByRank) so the best matches come first.
+func Rank(match Expression) TSRankColumn {
+	return TSRankColumn{
+		match: match,
+	}
+}
+
+// OrderByRank orders results by the ts_rank of match, best matches first.
+func OrderByRank(match Expression) Expression {
+	return OrderByDesc(Rank(match))
+}
+
+func (b *sqlBuilder) appendTSVectorColumn(c TSVectorColumn) error {
+	if len(c.columns) == 0 {
+		return fmt.Errorf("full-text search must have at least one column")
+	}
+
+	if c.stored {
+		return b.appendColumn(c.columns[0])
+	}
+
+	b.sb.WriteString("to_tsvector(")
+	b.appendTSConfig(c.config)
+
+	for i, column := range c.columns {
+		if i > 0 {
+			b.sb.WriteString(" || ' ' || ")
+		}
+
+		b.sb.WriteString("coalesce(")
+
+		err := b.appendColumn(column)
+		if err != nil {
+			return err
+		}
+
+		b.sb.WriteString("::text, '')")
+	}
+
+	b.sb.WriteByte(')')
+
+	return nil
+}
+
+func (b *sqlBuilder) appendTSQuery(config string, query interface{}) {
+	b.sb.WriteString("websearch_to_tsquery(")
+	b.appendTSConfig(config)
+	b.sb.WriteString("?)")
+	b.params = append(b.params, query)
+}
+
+// appendTSConfig writes the optional config argument of to_tsvector, websearch_to_tsquery, etc.
+func (b *sqlBuilder) appendTSConfig(config string) {
+	if len(config) == 0 {
+		return
+	}
+
+	b.sb.WriteString("?, ")
+	b.params = append(b.params, config)
+}
+
+func (b *sqlBuilder) appendMatches(e Expression) error {
+	c, ok := e.column.(TSVectorColumn)
+	if !ok {
+		return fmt.Errorf("%s must be used with Matches or MatchesVector", OpMatches)
+	}
+
+	err := b.appendTSVectorColumn(c)
+	if err != nil {
+		return err
+	}
+
+	b.sb.WriteString(" @@ ")
+	b.appendTSQuery(c.config, e.value)
+
+	return nil
+}
+
+func (b *sqlBuilder) appendTSRankColumn(c TSRankColumn) error {
+	vector, ok := c.match.column.(TSVectorColumn)
+	if !ok || c.match.op != OpMatches {
+		return fmt.Errorf("rank must be used with Matches or MatchesVector")
+	}
+
+	b.sb.WriteString("ts_rank(")
+
+	err := b.appendTSVectorColumn(vector)
+	if err != nil {
+		return err
+	}
+
+	b.sb.WriteString(", ")
+	b.appendTSQuery(vector.config, c.match.value)
+	b.sb.WriteByte(')')
+
+	return nil
+}
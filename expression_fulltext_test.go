@@ -0,0 +1,153 @@
+package milo
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := Matches([]interface{}{"foo", "bar"}, "baz", "english")
+
+	assert.Equal(Expression{
+		column: TSVectorColumn{columns: []interface{}{"foo", "bar"}, config: "english"},
+		op:     OpMatches,
+		value:  "baz",
+	}, actual)
+}
+
+func TestMatchesVector(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := MatchesVector("foo", "baz", "english")
+
+	assert.Equal(Expression{
+		column: TSVectorColumn{columns: []interface{}{"foo"}, config: "english", stored: true},
+		op:     OpMatches,
+		value:  "baz",
+	}, actual)
+}
+
+func TestOrderByRank(t *testing.T) {
+	assert := assert.New(t)
+
+	match := Matches([]interface{}{"foo"}, "baz", "english")
+	actual := OrderByRank(match)
+
+	assert.Equal(Expression{column: TSRankColumn{match: match}, op: orderDesc, t: expressionTypeOrder}, actual)
+}
+
+func TestBuildCondition_FullText(t *testing.T) {
+	tests := []struct {
+		name           string
+		expr           Expression
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:           "one column",
+			expr:           Matches([]interface{}{"name"}, "jane", "english"),
+			expectedSQL:    `to_tsvector(?, coalesce(t."name"::text, '')) @@ websearch_to_tsquery(?, ?)`,
+			expectedParams: []interface{}{"english", "english", "jane"},
+		},
+		{
+			name:           "multiple columns",
+			expr:           Matches([]interface{}{"name_first", "name_last"}, `"jane doe" -smith`, "simple"),
+			expectedSQL:    `to_tsvector(?, coalesce(t."name_first"::text, '') || ' ' || coalesce(t."name_last"::text, '')) @@ websearch_to_tsquery(?, ?)`,
+			expectedParams: []interface{}{"simple", "simple", `"jane doe" -smith`},
+		},
+		{
+			name:           "default config",
+			expr:           Matches([]interface{}{"name"}, "jane", ""),
+			expectedSQL:    `to_tsvector(coalesce(t."name"::text, '')) @@ websearch_to_tsquery(?)`,
+			expectedParams: []interface{}{"jane"},
+		},
+		{
+			name:           "stored vector",
+			expr:           MatchesVector("search", "jane", "english"),
+			expectedSQL:    `t."search" @@ websearch_to_tsquery(?, ?)`,
+			expectedParams: []interface{}{"english", "jane"},
+		},
+		{
+			name:           "json path",
+			expr:           Matches([]interface{}{JSONPath("answers", "notes")}, "anxiety", "english"),
+			expectedSQL:    `to_tsvector(?, coalesce(t."answers"->>?::text, '')) @@ websearch_to_tsquery(?, ?)`,
+			expectedParams: []interface{}{"english", "notes", "english", "anxiety"},
+		},
+		{
+			name:           "in groups",
+			expr:           Or(MatchesVector("search", "jane", "english"), And(Equal("a", 1), Matches([]interface{}{"b"}, "c", ""))),
+			expectedSQL:    `(t."search" @@ websearch_to_tsquery(?, ?) OR (t."a" = ? AND to_tsvector(coalesce(t."b"::text, '')) @@ websearch_to_tsquery(?)))`,
+			expectedParams: []interface{}{"english", "jane", 1, "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+			assert.Equal(tt.expectedSQL, sql)
+			assert.Equal(tt.expectedParams, params)
+		})
+	}
+}
+
+func TestBuildCondition_FullTextErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := buildCondition("t", Matches(nil, "jane", "english"))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", Expression{column: "name", op: OpMatches, value: "jane"})
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", And(OrderBy("name")))
+	assert.Error(err)
+}
+
+func TestBuildOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	sql, params, err := buildOrder("t", OrderBy("name"))
+	assert.NoError(err)
+	assert.Equal(`t."name" ASC`, sql)
+	assert.Empty(params)
+
+	sql, params, err = buildOrder("t", OrderByDesc("name"))
+	assert.NoError(err)
+	assert.Equal(`t."name" DESC`, sql)
+	assert.Empty(params)
+
+	sql, params, err = buildOrder("t", OrderByRank(Matches([]interface{}{"name"}, "jane", "english")))
+	assert.NoError(err)
+	assert.Equal(`ts_rank(to_tsvector(?, coalesce(t."name"::text, '')), websearch_to_tsquery(?, ?)) DESC`, sql)
+	assert.Equal([]interface{}{"english", "english", "jane"}, params)
+
+	_, _, err = buildOrder("t", OrderByDesc(Rank(Equal("name", "jane"))))
+	assert.Error(err)
+}
+
+func TestApplyExpressionsToQuery_FullText(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	match := Matches([]interface{}{"name_first", "name_last"}, "jane's", "english")
+
+	err := applyExpressionsToQuery([]Expression{
+		match,
+		OrderByRank(match),
+		OrderBy("name_last"),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE ((to_tsvector('english', coalesce("expression_model"."name_first"::text, '') || ' ' || coalesce("expression_model"."name_last"::text, '')) @@ websearch_to_tsquery('english', 'jane''s')))`)
+	assert.Contains(string(b), `ORDER BY ts_rank(to_tsvector('english', coalesce("expression_model"."name_first"::text, '') || ' ' || coalesce("expression_model"."name_last"::text, '')), websearch_to_tsquery('english', 'jane''s')) DESC, "expression_model"."name_last" ASC`)
+}
@@ -0,0 +1,105 @@
+package milo
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var funcNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// FuncColumn is a column reference that wraps columns and values in a SQL function call. See Fn.
+type FuncColumn struct {
+	name string
+	args []interface{}
+}
+
+// Fn returns a column reference for a call to the SQL function name. Each of args is rendered as a column if it is a
+// string, a Column or another column reference (e.g., the result of Fn or JSONPath) and is bound as a parameter
+// otherwise. Use Literal to bind a string as a parameter. name may be schema qualified and must be a plain identifier.
+// For example, Fn("round", "amount", 2) renders as round(alias."amount", 2).
+func Fn(name string, args ...interface{}) FuncColumn {
+	return FuncColumn{
+		name: name,
+		args: args,
+	}
+}
+
+func (c FuncColumn) Name() string {
+	return c.name
+}
+
+func (c FuncColumn) Args() []interface{} {
+	return c.args
+}
+
+// Lower returns a column reference for lower(column).
+func Lower(column interface{}) FuncColumn {
+	return Fn("lower", column)
+}
+
+// Upper returns a column reference for upper(column).
+func Upper(column interface{}) FuncColumn {
+	return Fn("upper", column)
+}
+
+// Coalesce returns a column reference for coalesce(args...). Like Fn, use Literal for a string fallback value.
+func Coalesce(args ...interface{}) FuncColumn {
+	return Fn("coalesce", args...)
+}
+
+// DateTrunc returns a column reference for date_trunc(field, column). field is a precision such as day or month.
+func DateTrunc(field string, column interface{}) FuncColumn {
+	return Fn("date_trunc", Literal(field), column)
+}
+
+// LiteralValue is a function argument that is always bound as a parameter. See Literal.
+type LiteralValue struct {
+	value interface{}
+}
+
+// Literal marks value as a parameter when passed to Fn (and the functions built on it), so strings aren't treated
+// as column names.
+func Literal(value interface{}) LiteralValue {
+	return LiteralValue{
+		value: value,
+	}
+}
+
+func (l LiteralValue) Value() interface{} {
+	return l.value
+}
+
+func (b *sqlBuilder) appendFuncColumn(c FuncColumn) error {
+	if !funcNameRegexp.MatchString(c.name) {
+		return fmt.Errorf("invalid function name %q", c.name)
+	}
+
+	b.sb.WriteString(c.name)
+	b.sb.WriteByte('(')
+
+	for i, arg := range c.args {
+		if i > 0 {
+			b.sb.WriteString(", ")
+		}
+
+		if isColumn(arg) {
+			err := b.appendColumn(arg)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if l, ok := arg.(LiteralValue); ok {
+			arg = l.value
+		}
+
+		b.sb.WriteByte('?')
+		b.params = append(b.params, arg)
+	}
+
+	b.sb.WriteByte(')')
+
+	return nil
+}
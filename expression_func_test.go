@@ -0,0 +1,189 @@
+package milo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFn(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := Fn("round", "foo", 2)
+
+	assert.Equal(FuncColumn{name: "round", args: []interface{}{"foo", 2}}, actual)
+	assert.Equal("round", actual.Name())
+	assert.Equal([]interface{}{"foo", 2}, actual.Args())
+}
+
+func TestLower(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := Lower("foo")
+
+	assert.Equal(FuncColumn{name: "lower", args: []interface{}{"foo"}}, actual)
+}
+
+func TestUpper(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := Upper("foo")
+
+	assert.Equal(FuncColumn{name: "upper", args: []interface{}{"foo"}}, actual)
+}
+
+func TestCoalesce(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := Coalesce("foo", Literal("bar"))
+
+	assert.Equal(FuncColumn{name: "coalesce", args: []interface{}{"foo", LiteralValue{value: "bar"}}}, actual)
+}
+
+func TestDateTrunc(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := DateTrunc("day", "foo")
+
+	assert.Equal(FuncColumn{name: "date_trunc", args: []interface{}{LiteralValue{value: "day"}, "foo"}}, actual)
+}
+
+func TestBuildCondition_Func(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		expr           Expression
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:           "lower",
+			expr:           Equal(Lower("email"), "jane@example.com"),
+			expectedSQL:    `lower(t."email") = ?`,
+			expectedParams: []interface{}{"jane@example.com"},
+		},
+		{
+			name:           "date trunc",
+			expr:           Equal(DateTrunc("day", "created_at"), day),
+			expectedSQL:    `date_trunc(?, t."created_at") = ?`,
+			expectedParams: []interface{}{"day", day},
+		},
+		{
+			name:           "coalesce",
+			expr:           Equal(Coalesce("name_last", "name_first", Literal("")), "Doe"),
+			expectedSQL:    `coalesce(t."name_last", t."name_first", ?) = ?`,
+			expectedParams: []interface{}{"", "Doe"},
+		},
+		{
+			name:           "nested",
+			expr:           NotEqual(Lower(Coalesce("email", Literal("none"))), "none"),
+			expectedSQL:    `lower(coalesce(t."email", ?)) != ?`,
+			expectedParams: []interface{}{"none", "none"},
+		},
+		{
+			name:           "generic with values",
+			expr:           Gt(Fn("round", "amount", 2), 10.5),
+			expectedSQL:    `round(t."amount", ?) > ?`,
+			expectedParams: []interface{}{2, 10.5},
+		},
+		{
+			name:           "schema qualified",
+			expr:           IsNull(Fn("public.normalize_phone", "phone")),
+			expectedSQL:    `public.normalize_phone(t."phone") IS NULL`,
+			expectedParams: nil,
+		},
+		{
+			name:           "no args",
+			expr:           Gte(DateTrunc("day", Fn("now")), day),
+			expectedSQL:    `date_trunc(?, now()) >= ?`,
+			expectedParams: []interface{}{"day", day},
+		},
+		{
+			name:           "json path",
+			expr:           Equal(Lower(JSONPath("answers", "name")), "jane"),
+			expectedSQL:    `lower(t."answers"->>?) = ?`,
+			expectedParams: []interface{}{"name", "jane"},
+		},
+		{
+			name:           "full-text search",
+			expr:           Matches([]interface{}{Lower("name")}, "jane", ""),
+			expectedSQL:    `to_tsvector(coalesce(lower(t."name")::text, '')) @@ websearch_to_tsquery(?)`,
+			expectedParams: []interface{}{"jane"},
+		},
+		{
+			name:           "in groups",
+			expr:           Or(Equal(Lower("email"), "a"), And(Equal(Upper("state"), "MA"), Gte(DateTrunc("month", "created_at"), day))),
+			expectedSQL:    `(lower(t."email") = ? OR (upper(t."state") = ? AND date_trunc(?, t."created_at") >= ?))`,
+			expectedParams: []interface{}{"a", "MA", "month", day},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+			assert.Equal(tt.expectedSQL, sql)
+			assert.Equal(tt.expectedParams, params)
+		})
+	}
+}
+
+func TestBuildCondition_FuncErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range []string{"", "lower(", "lower; DROP TABLE users", "1lower", "a.b.c", `"lower"`} {
+		_, _, err := buildCondition("t", Equal(Fn(name, "email"), "a"))
+		assert.Error(err, name)
+	}
+}
+
+func TestBuildCondition_QuotesIdentifiers(t *testing.T) {
+	assert := assert.New(t)
+
+	sql, params, err := buildCondition("t", Equal(Lower(`a"; DROP TABLE users; --`), "b"))
+	assert.NoError(err)
+	assert.Equal(`lower(t."a""; DROP TABLE users; --") = ?`, sql)
+	assert.Equal([]interface{}{"b"}, params)
+
+	sql, params, err = buildCondition("t", Equal("a?", "b"))
+	assert.NoError(err)
+	assert.Equal(`t."a\?" = ?`, sql)
+	assert.Equal([]interface{}{"b"}, params)
+}
+
+func TestBuildOrder_Func(t *testing.T) {
+	assert := assert.New(t)
+
+	sql, params, err := buildOrder("t", OrderBy(Lower("name_last")))
+	assert.NoError(err)
+	assert.Equal(`lower(t."name_last") ASC`, sql)
+	assert.Empty(params)
+
+	sql, params, err = buildOrder("t", OrderByDesc(DateTrunc("day", "created_at")))
+	assert.NoError(err)
+	assert.Equal(`date_trunc(?, t."created_at") DESC`, sql)
+	assert.Equal([]interface{}{"day"}, params)
+}
+
+func TestApplyExpressionsToQuery_Func(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		Equal(Lower("name_first"), "jane"),
+		Equal(Coalesce("name_last", Literal("it's")), "it's"),
+		OrderBy(DateTrunc("day", "created_at")),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE ((lower("expression_model"."name_first") = 'jane' AND coalesce("expression_model"."name_last", 'it''s') = 'it''s'))`)
+	assert.Contains(string(b), `ORDER BY date_trunc('day', "expression_model"."created_at") ASC`)
+}
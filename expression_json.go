@@ -0,0 +1,905 @@
+package milo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/pkg/errors"
+)
+
+// Expressions are encoded as JSON objects of one of these forms:
+//
+//	{"and": [...]}
+//	{"or": [...]}
+//	{"column": column, "op": "=", "value": {"type": "string", "value": "Jane"}}
+//	{"order_by": column, "desc": true}
+//
+// A column is either a column name or one of {"column": column, "path": ["a", 0]} (see JSONPath),
+// {"tsvector": [column, ...], "config": "english", "stored": false} (see Matches and MatchesVector) and
+// {"rank": expression} (see Rank). A value is tagged with its type, one of string, int, uint, float, bool, time
+// (RFC 3339), json (the document passed to JSONContains) or array (see ArrayContains), which also has an "elem" type,
+// or null for a nil value, e.g., of Equal(column, nil).
+// Raw expressions and function columns (see Fn) can't be encoded.
+
+const (
+	jsonValueString = "string"
+	jsonValueInt    = "int"
+	jsonValueUint   = "uint"
+	jsonValueFloat  = "float"
+	jsonValueBool   = "bool"
+	jsonValueTime   = "time"
+	jsonValueJSON   = "json"
+	jsonValueArray  = "array"
+)
+
+// knownOps are the operators an encoded condition may use.
+var knownOps = map[Op]bool{
+	OpIsNull:      true,
+	OpIsNotNull:   true,
+	OpEqual:       true,
+	OpNotEqual:    true,
+	OpGt:          true,
+	OpLt:          true,
+	OpGte:         true,
+	OpLte:         true,
+	OpContains:    true,
+	OpHasKey:      true,
+	OpHasAnyKeys:  true,
+	OpHasAllKeys:  true,
+	OpPathMatches: true,
+	OpContainedBy: true,
+	OpOverlaps:    true,
+	OpAnyEqual:    true,
+	OpMatches:     true,
+}
+
+type jsonExpression struct {
+	And     *[]Expression   `json:"and,omitempty"`
+	Or      *[]Expression   `json:"or,omitempty"`
+	Column  json.RawMessage `json:"column,omitempty"`
+	Op      Op              `json:"op,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	OrderBy json.RawMessage `json:"order_by,omitempty"`
+	Desc    bool            `json:"desc,omitempty"`
+}
+
+type jsonValue struct {
+	Type  string          `json:"type"`
+	Elem  string          `json:"elem,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonPathColumn struct {
+	Column json.RawMessage `json:"column"`
+	Path   []interface{}   `json:"path"`
+}
+
+type jsonTSVectorColumn struct {
+	TSVector []json.RawMessage `json:"tsvector"`
+	Config   string            `json:"config,omitempty"`
+	Stored   bool              `json:"stored,omitempty"`
+}
+
+type jsonTSRankColumn struct {
+	Rank Expression `json:"rank"`
+}
+
+// MarshalJSON encodes e so it can be stored or sent to another service and decoded with UnmarshalJSON or an
+// ExpressionDecoder.
+func (e Expression) MarshalJSON() ([]byte, error) {
+	j := jsonExpression{}
+
+	switch e.t {
+	case expressionTypeAnd:
+		exprs := e.exprs
+		if exprs == nil {
+			exprs = []Expression{}
+		}
+
+		j.And = &exprs
+
+	case expressionTypeOr:
+		exprs := e.exprs
+		if exprs == nil {
+			exprs = []Expression{}
+		}
+
+		j.Or = &exprs
+
+	case expressionTypeCondition:
+		column, err := marshalColumn(e.column)
+		if err != nil {
+			return nil, err
+		}
+
+		j.Column = column
+		j.Op = e.op
+
+		// A nil value, e.g., of Equal(column, nil), is encoded as null so it's told apart from IS NULL, which has no
+		// value.
+		if e.value == nil && e.op != OpIsNull && e.op != OpIsNotNull {
+			j.Value = json.RawMessage("null")
+		}
+
+		if e.value != nil {
+			value, err := marshalValue(e.op, e.value)
+			if err != nil {
+				return nil, err
+			}
+
+			j.Value, err = json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	case expressionTypeOrder:
+		column, err := marshalColumn(e.column)
+		if err != nil {
+			return nil, err
+		}
+
+		j.OrderBy = column
+		j.Desc = e.op == orderDesc
+
+	case expressionTypeRaw:
+		return nil, errors.New("raw expressions can't be encoded as JSON")
+
+	default:
+		return nil, fmt.Errorf("unknown expressionType: %s", reflect.TypeOf(e.t).String())
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes data produced by MarshalJSON. Any operator and column is allowed; use an ExpressionDecoder to
+// decode untrusted input.
+func (e *Expression) UnmarshalJSON(data []byte) error {
+	expr, err := ExpressionDecoder{}.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	*e = expr
+
+	return nil
+}
+
+func marshalColumn(column interface{}) (json.RawMessage, error) {
+	switch c := column.(type) {
+	case string:
+		return json.Marshal(c)
+
+	case Column:
+		return json.Marshal(string(c))
+
+	case JSONPathColumn:
+		inner, err := marshalColumn(c.column)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(jsonPathColumn{
+			Column: inner,
+			Path:   c.path,
+		})
+
+	case TSVectorColumn:
+		j := jsonTSVectorColumn{
+			TSVector: make([]json.RawMessage, len(c.columns)),
+			Config:   c.config,
+			Stored:   c.stored,
+		}
+
+		for i, column := range c.columns {
+			inner, err := marshalColumn(column)
+			if err != nil {
+				return nil, err
+			}
+
+			j.TSVector[i] = inner
+		}
+
+		return json.Marshal(j)
+
+	case TSRankColumn:
+		return json.Marshal(jsonTSRankColumn{
+			Rank: c.match,
+		})
+
+	default:
+		v := reflect.ValueOf(column)
+		if v.Kind() != reflect.String {
+			return nil, fmt.Errorf("column type %T can't be encoded as JSON", column)
+		}
+
+		return json.Marshal(v.String())
+	}
+}
+
+func marshalValue(op Op, value interface{}) (*jsonValue, error) {
+	array, isArray := value.(*types.Array)
+	if isArray {
+		value = array.Value()
+	}
+
+	// JSONHasAnyKeys and JSONHasAllKeys keep their keys as a plain []string.
+	if isArray || op == OpHasAnyKeys || op == OpHasAllKeys {
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("array value %T can't be encoded as JSON", value)
+		}
+
+		elem, err := jsonValueType(v.Type().Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]json.RawMessage, v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			values[i], err = marshalScalar(elem, v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		b, err := json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+
+		return &jsonValue{
+			Type:  jsonValueArray,
+			Elem:  elem,
+			Value: b,
+		}, nil
+	}
+
+	if op == OpContains {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding %T as json", value)
+		}
+
+		return &jsonValue{
+			Type:  jsonValueJSON,
+			Value: b,
+		}, nil
+	}
+
+	t, err := jsonValueType(reflect.TypeOf(value))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := marshalScalar(t, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonValue{
+		Type:  t,
+		Value: b,
+	}, nil
+}
+
+func jsonValueType(t reflect.Type) (string, error) {
+	if t == reflect.TypeOf(time.Time{}) {
+		return jsonValueTime, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return jsonValueString, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return jsonValueInt, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonValueUint, nil
+
+	case reflect.Float32, reflect.Float64:
+		return jsonValueFloat, nil
+
+	case reflect.Bool:
+		return jsonValueBool, nil
+
+	default:
+		return "", fmt.Errorf("value type %s can't be encoded as JSON", t)
+	}
+}
+
+func marshalScalar(t string, value interface{}) (json.RawMessage, error) {
+	v := reflect.ValueOf(value)
+
+	switch t {
+	case jsonValueTime:
+		return json.Marshal(value.(time.Time).Format(time.RFC3339Nano))
+
+	case jsonValueString:
+		return json.Marshal(v.String())
+
+	case jsonValueInt:
+		return json.Marshal(v.Int())
+
+	case jsonValueUint:
+		return json.Marshal(v.Uint())
+
+	case jsonValueFloat:
+		return json.Marshal(v.Float())
+
+	default:
+		return json.Marshal(v.Bool())
+	}
+}
+
+// ExpressionDecoder decodes expressions encoded with Expression.MarshalJSON. Decoding is strict: unknown keys,
+// unknown operators, mistyped values and trailing data are errors. Raw expressions and function columns are never
+// decoded, and values are always bound as parameters when the expression is applied to a query.
+type ExpressionDecoder struct {
+	// Ops are the operators a decoded condition may use. If Ops is empty, every operator is allowed.
+	Ops []Op
+	// Columns are the column names a decoded expression may reference, including the columns inside JSON paths,
+	// full-text searches and ranks. If Columns is empty, every column of the queried model is allowed.
+	Columns []string
+}
+
+// Decode decodes data into an Expression. Errors include the JSON path of the offending element (e.g., $.and[1].op).
+func (d ExpressionDecoder) Decode(data []byte) (Expression, error) {
+	dec := &expressionDecoder{
+		ops:     make(map[Op]bool, len(d.Ops)),
+		columns: make(map[string]bool, len(d.Columns)),
+	}
+
+	for _, op := range d.Ops {
+		dec.ops[op] = true
+	}
+
+	for _, column := range d.Columns {
+		dec.columns[column] = true
+	}
+
+	return dec.decodeExpression("$", data)
+}
+
+type expressionDecoder struct {
+	ops     map[Op]bool
+	columns map[string]bool
+}
+
+func (d *expressionDecoder) decodeExpression(path string, data []byte) (Expression, error) {
+	obj, err := decodeObject(path, data, "and", "or", "column", "op", "value", "order_by", "desc")
+	if err != nil {
+		return Expression{}, err
+	}
+
+	switch {
+	case has(obj, "and") || has(obj, "or"):
+		key := "and"
+		t := expressionTypeAnd
+
+		if has(obj, "or") {
+			key = "or"
+			t = expressionTypeOr
+		}
+
+		if len(obj) != 1 {
+			return Expression{}, fmt.Errorf("%s: %q can't be combined with other keys", path, key)
+		}
+
+		var raw []json.RawMessage
+
+		err = unmarshalStrict(obj[key], &raw)
+		if err != nil || raw == nil {
+			return Expression{}, fmt.Errorf("%s.%s: expected an array of expressions", path, key)
+		}
+
+		var exprs []Expression
+
+		if len(raw) > 0 {
+			exprs = make([]Expression, len(raw))
+		}
+
+		for i, r := range raw {
+			exprs[i], err = d.decodeExpression(fmt.Sprintf("%s.%s[%d]", path, key, i), r)
+			if err != nil {
+				return Expression{}, err
+			}
+		}
+
+		return Expression{
+			t:     t,
+			exprs: exprs,
+		}, nil
+
+	case has(obj, "order_by"):
+		err = onlyKeys(path, obj, "order_by", "desc")
+		if err != nil {
+			return Expression{}, err
+		}
+
+		column, err := d.decodeColumn(path+".order_by", obj["order_by"])
+		if err != nil {
+			return Expression{}, err
+		}
+
+		desc := false
+
+		if has(obj, "desc") {
+			err = unmarshalStrict(obj["desc"], &desc)
+			if err != nil {
+				return Expression{}, fmt.Errorf("%s.desc: expected a boolean", path)
+			}
+		}
+
+		if desc {
+			return OrderByDesc(column), nil
+		}
+
+		return OrderBy(column), nil
+
+	case has(obj, "column"):
+		err = onlyKeys(path, obj, "column", "op", "value")
+		if err != nil {
+			return Expression{}, err
+		}
+
+		return d.decodeCondition(path, obj)
+
+	default:
+		return Expression{}, fmt.Errorf("%s: expected one of \"and\", \"or\", \"column\" or \"order_by\"", path)
+	}
+}
+
+func (d *expressionDecoder) decodeCondition(path string, obj map[string]json.RawMessage) (Expression, error) {
+	column, err := d.decodeColumn(path+".column", obj["column"])
+	if err != nil {
+		return Expression{}, err
+	}
+
+	var op Op
+
+	err = unmarshalStrict(obj["op"], &op)
+	if err != nil || len(op) == 0 {
+		return Expression{}, fmt.Errorf("%s.op: expected an operator", path)
+	}
+
+	if !knownOps[op] {
+		return Expression{}, fmt.Errorf("%s.op: unknown operator %q", path, op)
+	}
+
+	if len(d.ops) > 0 && !d.ops[op] {
+		return Expression{}, fmt.Errorf("%s.op: operator %q is not allowed", path, op)
+	}
+
+	_, isTSVector := column.(TSVectorColumn)
+	if isTSVector != (op == OpMatches) {
+		return Expression{}, fmt.Errorf("%s.op: operator %q can't be used with this column", path, op)
+	}
+
+	e := Expression{
+		column: column,
+		op:     op,
+	}
+
+	if op == OpIsNull || op == OpIsNotNull {
+		if has(obj, "value") {
+			return Expression{}, fmt.Errorf("%s.value: operator %q doesn't take a value", path, op)
+		}
+
+		return e, nil
+	}
+
+	if !has(obj, "value") {
+		return Expression{}, fmt.Errorf("%s.value: operator %q requires a value", path, op)
+	}
+
+	if bytes.Equal(bytes.TrimSpace(obj["value"]), []byte("null")) {
+		return e, nil
+	}
+
+	e.value, err = decodeValue(path+".value", op, obj["value"])
+	if err != nil {
+		return Expression{}, err
+	}
+
+	return e, nil
+}
+
+func (d *expressionDecoder) decodeColumn(path string, data json.RawMessage) (interface{}, error) {
+	var name string
+
+	if unmarshalStrict(data, &name) == nil {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("%s: column name can't be empty", path)
+		}
+
+		if len(d.columns) > 0 && !d.columns[name] {
+			return nil, fmt.Errorf("%s: column %q is not allowed", path, name)
+		}
+
+		return name, nil
+	}
+
+	obj, err := decodeObject(path, data, "column", "path", "tsvector", "config", "stored", "rank")
+	if err != nil {
+		return nil, fmt.Errorf("%s: expected a column name or object", path)
+	}
+
+	switch {
+	case has(obj, "path"):
+		err = onlyKeys(path, obj, "column", "path")
+		if err != nil {
+			return nil, err
+		}
+
+		column, err := d.decodeColumn(path+".column", obj["column"])
+		if err != nil {
+			return nil, err
+		}
+
+		var raw []json.RawMessage
+
+		err = unmarshalStrict(obj["path"], &raw)
+		if err != nil || len(raw) == 0 {
+			return nil, fmt.Errorf("%s.path: expected a non-empty array of keys and indices", path)
+		}
+
+		steps := make([]interface{}, len(raw))
+
+		for i, r := range raw {
+			var key string
+			var index int
+
+			if unmarshalStrict(r, &key) == nil {
+				steps[i] = key
+			} else if unmarshalStrict(r, &index) == nil {
+				steps[i] = index
+			} else {
+				return nil, fmt.Errorf("%s.path[%d]: expected a string key or an integer index", path, i)
+			}
+		}
+
+		return JSONPath(column, steps...), nil
+
+	case has(obj, "tsvector"):
+		err = onlyKeys(path, obj, "tsvector", "config", "stored")
+		if err != nil {
+			return nil, err
+		}
+
+		var raw []json.RawMessage
+
+		err = unmarshalStrict(obj["tsvector"], &raw)
+		if err != nil || len(raw) == 0 {
+			return nil, fmt.Errorf("%s.tsvector: expected a non-empty array of columns", path)
+		}
+
+		c := TSVectorColumn{
+			columns: make([]interface{}, len(raw)),
+		}
+
+		for i, r := range raw {
+			c.columns[i], err = d.decodeColumn(fmt.Sprintf("%s.tsvector[%d]", path, i), r)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if has(obj, "config") {
+			err = unmarshalStrict(obj["config"], &c.config)
+			if err != nil {
+				return nil, fmt.Errorf("%s.config: expected a string", path)
+			}
+		}
+
+		if has(obj, "stored") {
+			err = unmarshalStrict(obj["stored"], &c.stored)
+			if err != nil {
+				return nil, fmt.Errorf("%s.stored: expected a boolean", path)
+			}
+		}
+
+		if c.stored && len(c.columns) != 1 {
+			return nil, fmt.Errorf("%s.tsvector: a stored tsvector must have exactly one column", path)
+		}
+
+		return c, nil
+
+	case has(obj, "rank"):
+		err = onlyKeys(path, obj, "rank")
+		if err != nil {
+			return nil, err
+		}
+
+		match, err := d.decodeExpression(path+".rank", obj["rank"])
+		if err != nil {
+			return nil, err
+		}
+
+		if match.t != expressionTypeCondition || match.op != OpMatches {
+			return nil, fmt.Errorf("%s.rank: expected a full-text search condition", path)
+		}
+
+		return Rank(match), nil
+
+	default:
+		return nil, fmt.Errorf("%s: expected one of \"path\", \"tsvector\" or \"rank\"", path)
+	}
+}
+
+func decodeValue(path string, op Op, data json.RawMessage) (interface{}, error) {
+	obj, err := decodeObject(path, data, "type", "elem", "value")
+	if err != nil {
+		return nil, err
+	}
+
+	var v jsonValue
+
+	err = unmarshalStrict(obj["type"], &v.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%s.type: expected a string", path)
+	}
+
+	if !has(obj, "value") {
+		return nil, fmt.Errorf("%s.value: missing value", path)
+	}
+
+	v.Value = obj["value"]
+
+	switch v.Type {
+	case jsonValueJSON:
+		if op != OpContains {
+			return nil, fmt.Errorf("%s.type: operator %q doesn't take a json value", path, op)
+		}
+
+		if has(obj, "elem") {
+			return nil, fmt.Errorf("%s: unexpected key \"elem\"", path)
+		}
+
+		var b bytes.Buffer
+
+		err = json.Compact(&b, v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.value: %s", path, err)
+		}
+
+		return json.RawMessage(b.Bytes()), nil
+
+	case jsonValueArray:
+		switch op {
+		case OpContains, OpContainedBy, OpOverlaps, OpHasAnyKeys, OpHasAllKeys:
+		default:
+			return nil, fmt.Errorf("%s.type: operator %q doesn't take an array value", path, op)
+		}
+
+		err = unmarshalStrict(obj["elem"], &v.Elem)
+		if err != nil {
+			return nil, fmt.Errorf("%s.elem: expected a string", path)
+		}
+
+		if (op == OpHasAnyKeys || op == OpHasAllKeys) && v.Elem != jsonValueString {
+			return nil, fmt.Errorf("%s.elem: operator %q requires string keys", path, op)
+		}
+
+		values, err := decodeArray(path, v)
+		if err != nil {
+			return nil, err
+		}
+
+		if op == OpHasAnyKeys || op == OpHasAllKeys {
+			return values, nil
+		}
+
+		return pg.Array(values), nil
+
+	default:
+		if has(obj, "elem") {
+			return nil, fmt.Errorf("%s: unexpected key \"elem\"", path)
+		}
+
+		switch op {
+		case OpContainedBy, OpOverlaps, OpHasAnyKeys, OpHasAllKeys:
+			return nil, fmt.Errorf("%s.type: operator %q requires an array value", path, op)
+
+		case OpContains:
+			return nil, fmt.Errorf("%s.type: operator %q requires a json or array value", path, op)
+
+		case OpHasKey, OpPathMatches, OpMatches:
+			if v.Type != jsonValueString {
+				return nil, fmt.Errorf("%s.type: operator %q requires a string value", path, op)
+			}
+		}
+
+		switch v.Type {
+		case jsonValueString, jsonValueInt, jsonValueUint, jsonValueFloat, jsonValueBool, jsonValueTime:
+		default:
+			return nil, fmt.Errorf("%s.type: unknown value type %q", path, v.Type)
+		}
+
+		return decodeScalar(path+".value", v.Type, v.Value)
+	}
+}
+
+func decodeArray(path string, v jsonValue) (interface{}, error) {
+	var raw []json.RawMessage
+
+	err := unmarshalStrict(v.Value, &raw)
+	if err != nil || raw == nil {
+		return nil, fmt.Errorf("%s.value: expected an array", path)
+	}
+
+	var values reflect.Value
+
+	switch v.Elem {
+	case jsonValueString:
+		values = reflect.ValueOf([]string{})
+	case jsonValueInt:
+		values = reflect.ValueOf([]int64{})
+	case jsonValueUint:
+		values = reflect.ValueOf([]uint64{})
+	case jsonValueFloat:
+		values = reflect.ValueOf([]float64{})
+	case jsonValueBool:
+		values = reflect.ValueOf([]bool{})
+	case jsonValueTime:
+		values = reflect.ValueOf([]time.Time{})
+	default:
+		return nil, fmt.Errorf("%s.elem: unknown element type %q", path, v.Elem)
+	}
+
+	for i, r := range raw {
+		value, err := decodeScalar(fmt.Sprintf("%s.value[%d]", path, i), v.Elem, r)
+		if err != nil {
+			return nil, err
+		}
+
+		values = reflect.Append(values, reflect.ValueOf(value))
+	}
+
+	return values.Interface(), nil
+}
+
+func decodeScalar(path string, t string, data json.RawMessage) (interface{}, error) {
+	switch t {
+	case jsonValueString:
+		var s string
+
+		err := unmarshalStrict(data, &s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a string", path)
+		}
+
+		return s, nil
+
+	case jsonValueInt:
+		i, err := strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected an integer", path)
+		}
+
+		return i, nil
+
+	case jsonValueUint:
+		u, err := strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected an unsigned integer", path)
+		}
+
+		return u, nil
+
+	case jsonValueFloat:
+		var f float64
+
+		err := unmarshalStrict(data, &f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a number", path)
+		}
+
+		return f, nil
+
+	case jsonValueBool:
+		var b bool
+
+		err := unmarshalStrict(data, &b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a boolean", path)
+		}
+
+		return b, nil
+
+	case jsonValueTime:
+		var s string
+
+		err := unmarshalStrict(data, &s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected an RFC 3339 time", path)
+		}
+
+		tm, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected an RFC 3339 time", path)
+		}
+
+		return tm, nil
+
+	default:
+		return nil, fmt.Errorf("%s: unknown value type %q", path, t)
+	}
+}
+
+// decodeObject decodes data as a JSON object whose keys must be in allowed.
+func decodeObject(path string, data []byte, allowed ...string) (map[string]json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+
+	err := unmarshalStrict(data, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("%s: expected an object", path)
+	}
+
+	return obj, onlyKeys(path, obj, allowed...)
+}
+
+func onlyKeys(path string, obj map[string]json.RawMessage, allowed ...string) error {
+	var unexpected []string
+
+	for key := range obj {
+		if !contains(allowed, key) {
+			unexpected = append(unexpected, key)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+
+		return fmt.Errorf("%s: unexpected key %q", path, unexpected[0])
+	}
+
+	return nil
+}
+
+// unmarshalStrict unmarshals data into v, but, unlike json.Unmarshal, rejects null and trailing data.
+func unmarshalStrict(data []byte, v interface{}) error {
+	if len(data) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return errors.New("unexpected null")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = dec.Token()
+	if err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+
+	return nil
+}
+
+func has(obj map[string]json.RawMessage, key string) bool {
+	_, ok := obj[key]
+	return ok
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
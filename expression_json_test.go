@@ -0,0 +1,247 @@
+package milo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression_MarshalJSON(t *testing.T) {
+	day := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     Expression
+		expected string
+	}{
+		{
+			name:     "equal",
+			expr:     Equal("name_first", "Jane"),
+			expected: `{"column":"name_first","op":"=","value":{"type":"string","value":"Jane"}}`,
+		},
+		{
+			name:     "typed column",
+			expr:     Col("age").Gte(21),
+			expected: `{"column":"age","op":">=","value":{"type":"int","value":21}}`,
+		},
+		{
+			name:     "is null",
+			expr:     IsNull("email"),
+			expected: `{"column":"email","op":"IS NULL"}`,
+		},
+		{
+			name:     "nil value",
+			expr:     Equal("email", nil),
+			expected: `{"column":"email","op":"=","value":null}`,
+		},
+		{
+			name:     "time",
+			expr:     Lt("created_at", day),
+			expected: `{"column":"created_at","op":"<","value":{"type":"time","value":"2021-06-01T12:30:00Z"}}`,
+		},
+		{
+			name:     "nested",
+			expr:     And(Equal("active", true), Or(Gt("score", 1.5), NotEqual("count", uint(3)))),
+			expected: `{"and":[{"column":"active","op":"=","value":{"type":"bool","value":true}},{"or":[{"column":"score","op":">","value":{"type":"float","value":1.5}},{"column":"count","op":"!=","value":{"type":"uint","value":3}}]}]}`,
+		},
+		{
+			name:     "empty group",
+			expr:     Or(),
+			expected: `{"or":[]}`,
+		},
+		{
+			name:     "array",
+			expr:     ArrayContains("tags", []string{"a", "b"}),
+			expected: `{"column":"tags","op":"@>","value":{"type":"array","elem":"string","value":["a","b"]}}`,
+		},
+		{
+			name:     "json contains",
+			expr:     JSONContains("answers", map[string]int{"a": 1}),
+			expected: `{"column":"answers","op":"@>","value":{"type":"json","value":{"a":1}}}`,
+		},
+		{
+			name:     "json path",
+			expr:     Equal(JSONPath("answers", "medications", 0, "name"), "aspirin"),
+			expected: `{"column":{"column":"answers","path":["medications",0,"name"]},"op":"=","value":{"type":"string","value":"aspirin"}}`,
+		},
+		{
+			name:     "order",
+			expr:     OrderByDesc("created_at"),
+			expected: `{"order_by":"created_at","desc":true}`,
+		},
+		{
+			name:     "rank",
+			expr:     OrderByRank(MatchesVector("search", "jane", "simple")),
+			expected: `{"order_by":{"rank":{"column":{"tsvector":["search"],"config":"simple","stored":true},"op":"@@","value":{"type":"string","value":"jane"}}},"desc":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			b, err := json.Marshal(tt.expr)
+			assert.NoError(err)
+			assert.JSONEq(tt.expected, string(b))
+		})
+	}
+}
+
+func TestExpression_MarshalJSONErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := json.Marshal(Raw("length(name) > ?", 3))
+	assert.Error(err)
+
+	_, err = json.Marshal(Equal(Lower("email"), "a"))
+	assert.Error(err)
+
+	_, err = json.Marshal(Equal("email", struct{}{}))
+	assert.Error(err)
+}
+
+func TestExpression_UnmarshalJSON(t *testing.T) {
+	day := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr Expression
+	}{
+		{"equal", Equal("name_first", "Jane")},
+		{"not equal", NotEqual("age", int64(30))},
+		{"is not null", IsNotNull("email")},
+		{"nil value", Equal("email", nil)},
+		{"time", Gte("created_at", day)},
+		{"nested", Or(And(Equal("a", true), Lte("b", 2.5)), And(), Gt("c", uint64(3)))},
+		{"json path", Equal(JSONPath("answers", "medications", 0, "name"), "aspirin")},
+		{"json keys", And(JSONHasKey("answers", "a"), JSONPathMatches("answers", "$.a ? (@ > 1)"))},
+		{"any equal", AnyEqual("tags", "a")},
+		{"full-text search", Matches([]interface{}{"name_first", JSONPath("answers", "name")}, "jane", "english")},
+		{"order", OrderBy("name_last")},
+		{"rank", OrderByRank(MatchesVector("search", "jane", ""))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			b, err := json.Marshal(tt.expr)
+			assert.NoError(err)
+
+			var actual Expression
+
+			err = json.Unmarshal(b, &actual)
+			assert.NoError(err)
+			assert.Equal(tt.expr, actual)
+		})
+	}
+}
+
+func TestExpression_UnmarshalJSONParams(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expression
+	}{
+		{"array contains", ArrayContains("tags", []string{"a", "b"})},
+		{"array overlaps", ArrayOverlaps("codes", []int{1, 2})},
+		{"array contained by", ArrayContainedBy("dates", []time.Time{time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)})},
+		{"json has any keys", JSONHasAnyKeys("answers", "a", "b")},
+		{"json contains", JSONContains("answers", map[string]interface{}{"a": []int{1}})},
+		{"typed column", Col("name").Eq("Jane").And(Col("age").Gt(3))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			b, err := json.Marshal(tt.expr)
+			assert.NoError(err)
+
+			var actual Expression
+
+			err = json.Unmarshal(b, &actual)
+			assert.NoError(err)
+
+			expectedSQL, expectedParams, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+
+			actualSQL, actualParams, err := buildCondition("t", actual)
+			assert.NoError(err)
+
+			assert.Equal(expectedSQL, actualSQL)
+			assert.Equal(len(expectedParams), len(actualParams))
+
+			for i := range expectedParams {
+				assert.EqualValues(formatParam(expectedParams[i]), formatParam(actualParams[i]))
+			}
+		})
+	}
+}
+
+func TestExpressionDecoder_Decode(t *testing.T) {
+	assert := assert.New(t)
+
+	d := ExpressionDecoder{
+		Ops:     []Op{OpEqual, OpIsNull},
+		Columns: []string{"name_first", "answers"},
+	}
+
+	actual, err := d.Decode([]byte(`{"or":[{"column":"name_first","op":"=","value":{"type":"string","value":"Jane"}},{"column":{"column":"answers","path":["a"]},"op":"IS NULL"}]}`))
+	assert.NoError(err)
+	assert.Equal(Or(Equal("name_first", "Jane"), IsNull(JSONPath("answers", "a"))), actual)
+}
+
+func TestExpressionDecoder_DecodeErrors(t *testing.T) {
+	d := ExpressionDecoder{
+		Ops:     []Op{OpEqual, OpIsNull, OpMatches},
+		Columns: []string{"name_first", "answers", "search"},
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"not an object", `[]`, `$: expected an object`},
+		{"null", `null`, `$: expected an object`},
+		{"trailing data", `{"and":[]} {}`, `$: expected an object`},
+		{"unknown key", `{"and":[],"sql":"1=1"}`, `$: unexpected key "sql"`},
+		{"mixed keys", `{"and":[],"column":"name_first"}`, `$: "and" can't be combined with other keys`},
+		{"empty", `{}`, `$: expected one of "and", "or", "column" or "order_by"`},
+		{"group not an array", `{"and":{}}`, `$.and: expected an array of expressions`},
+		{"column not allowed", `{"and":[{"column":"ssn","op":"=","value":{"type":"string","value":"1"}}]}`, `$.and[0].column: column "ssn" is not allowed`},
+		{"nested column not allowed", `{"column":{"column":"ssn","path":["a"]},"op":"IS NULL"}`, `$.column.column: column "ssn" is not allowed`},
+		{"op not allowed", `{"column":"name_first","op":">","value":{"type":"string","value":"a"}}`, `$.op: operator ">" is not allowed`},
+		{"unknown op", `{"column":"name_first","op":"; DROP TABLE users","value":{"type":"string","value":"a"}}`, `$.op: unknown operator "; DROP TABLE users"`},
+		{"missing op", `{"column":"name_first"}`, `$.op: expected an operator`},
+		{"missing value", `{"column":"name_first","op":"="}`, `$.value: operator "=" requires a value`},
+		{"unexpected value", `{"column":"name_first","op":"IS NULL","value":{"type":"string","value":"a"}}`, `$.value: operator "IS NULL" doesn't take a value`},
+		{"unknown value type", `{"column":"name_first","op":"=","value":{"type":"sql","value":"a"}}`, `$.value.type: unknown value type "sql"`},
+		{"mistyped value", `{"column":"name_first","op":"=","value":{"type":"int","value":"1"}}`, `$.value.value: expected an integer`},
+		{"null value", `{"column":"name_first","op":"=","value":{"type":"string","value":null}}`, `$.value.value: expected a string`},
+		{"json value", `{"column":"name_first","op":"=","value":{"type":"json","value":{}}}`, `$.value.type: operator "=" doesn't take a json value`},
+		{"empty column", `{"column":"","op":"IS NULL"}`, `$.column: column name can't be empty`},
+		{"bad path", `{"column":{"column":"answers","path":[1.5]},"op":"IS NULL"}`, `$.column.path[0]: expected a string key or an integer index`},
+		{"match without tsvector", `{"column":"name_first","op":"@@","value":{"type":"string","value":"a"}}`, `$.op: operator "@@" can't be used with this column`},
+		{"tsvector without match", `{"column":{"tsvector":["search"]},"op":"=","value":{"type":"string","value":"a"}}`, `$.op: operator "=" can't be used with this column`},
+		{"rank not a match", `{"order_by":{"rank":{"column":"name_first","op":"IS NULL"}}}`, `$.order_by.rank: expected a full-text search condition`},
+		{"raw", `{"raw":"1=1"}`, `$: unexpected key "raw"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := d.Decode([]byte(tt.data))
+			assert.EqualError(err, tt.expected)
+		})
+	}
+}
+
+// formatParam renders param as a SQL literal so params of different Go types can be compared.
+func formatParam(param interface{}) string {
+	return string(orm.NewFormatter().FormatQuery(nil, "?", param))
+}
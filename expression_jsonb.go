@@ -0,0 +1,112 @@
+package milo
+
+import (
+	"fmt"
+)
+
+const (
+	OpContains    Op = "@>"
+	OpHasKey      Op = "?"
+	OpHasAnyKeys  Op = "?|"
+	OpHasAllKeys  Op = "?&"
+	OpPathMatches Op = "@?"
+)
+
+// JSONPathColumn is a column reference that extracts a value from a jsonb column. See JSONPath.
+type JSONPathColumn struct {
+	column interface{}
+	path   []interface{}
+}
+
+// JSONPath returns a column reference for the value at path inside the jsonb column. Each element of path is an object
+// key (string) or an array index (int). The last element is extracted as text (->>), so the reference can be compared
+// to strings with Equal, NotEqual, etc. For example, JSONPath("answers", "a", "b") renders as answers->'a'->>'b'.
+func JSONPath(column interface{}, path ...interface{}) JSONPathColumn {
+	return JSONPathColumn{
+		column: column,
+		path:   path,
+	}
+}
+
+func (c JSONPathColumn) Column() interface{} {
+	return c.column
+}
+
+func (c JSONPathColumn) Path() []interface{} {
+	return c.path
+}
+
+// JSONContains matches rows where the jsonb column contains value (@>). value is encoded with encoding/json.
+func JSONContains(column interface{}, value interface{}) Expression {
+	return Expression{
+		column: column,
+		op:     OpContains,
+		value:  value,
+	}
+}
+
+// JSONHasKey matches rows where key is a top level key of the jsonb column (?).
+func JSONHasKey(column interface{}, key string) Expression {
+	return Expression{
+		column: column,
+		op:     OpHasKey,
+		value:  key,
+	}
+}
+
+// JSONHasAnyKeys matches rows where any of keys is a top level key of the jsonb column (?|).
+func JSONHasAnyKeys(column interface{}, keys ...string) Expression {
+	return Expression{
+		column: column,
+		op:     OpHasAnyKeys,
+		value:  keys,
+	}
+}
+
+// JSONHasAllKeys matches rows where all of keys are top level keys of the jsonb column (?&).
+func JSONHasAllKeys(column interface{}, keys ...string) Expression {
+	return Expression{
+		column: column,
+		op:     OpHasAllKeys,
+		value:  keys,
+	}
+}
+
+// JSONPathMatches matches rows where the jsonpath expression returns any item for the jsonb column (@?). For example,
+// JSONPathMatches("answers", "$.medications[*] ? (@.name == \"aspirin\")").
+func JSONPathMatches(column interface{}, path string) Expression {
+	return Expression{
+		column: column,
+		op:     OpPathMatches,
+		value:  path,
+	}
+}
+
+func (b *sqlBuilder) appendJSONPathColumn(c JSONPathColumn) error {
+	if len(c.path) == 0 {
+		return fmt.Errorf("json path for column %v must not be empty", c.column)
+	}
+
+	err := b.appendColumn(c.column)
+	if err != nil {
+		return err
+	}
+
+	for i, key := range c.path {
+		switch key.(type) {
+		case string, int:
+		default:
+			return fmt.Errorf("json path element %v for column %v must be a string or an int", key, c.column)
+		}
+
+		if i == len(c.path)-1 {
+			b.sb.WriteString("->>?")
+		} else {
+			b.sb.WriteString("->?")
+		}
+
+		b.params = append(b.params, key)
+	}
+
+	return nil
+}
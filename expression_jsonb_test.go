@@ -0,0 +1,171 @@
+package milo
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPath(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := JSONPath("foo", "bar", 0)
+
+	assert.Equal(JSONPathColumn{column: "foo", path: []interface{}{"bar", 0}}, actual)
+	assert.Equal("foo", actual.Column())
+	assert.Equal([]interface{}{"bar", 0}, actual.Path())
+}
+
+func TestJSONContains(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := JSONContains("foo", map[string]interface{}{"bar": "baz"})
+
+	assert.Equal(Expression{column: "foo", op: OpContains, value: map[string]interface{}{"bar": "baz"}}, actual)
+}
+
+func TestJSONHasKey(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := JSONHasKey("foo", "bar")
+
+	assert.Equal(Expression{column: "foo", op: OpHasKey, value: "bar"}, actual)
+}
+
+func TestJSONHasAnyKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := JSONHasAnyKeys("foo", "bar", "baz")
+
+	assert.Equal(Expression{column: "foo", op: OpHasAnyKeys, value: []string{"bar", "baz"}}, actual)
+}
+
+func TestJSONHasAllKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := JSONHasAllKeys("foo", "bar", "baz")
+
+	assert.Equal(Expression{column: "foo", op: OpHasAllKeys, value: []string{"bar", "baz"}}, actual)
+}
+
+func TestJSONPathMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := JSONPathMatches("foo", "$.bar")
+
+	assert.Equal(Expression{column: "foo", op: OpPathMatches, value: "$.bar"}, actual)
+}
+
+func TestBuildCondition_JSONB(t *testing.T) {
+	tests := []struct {
+		name           string
+		expr           Expression
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:           "path",
+			expr:           Equal(JSONPath("answers", "a", "b"), "yes"),
+			expectedSQL:    `t."answers"->?->>? = ?`,
+			expectedParams: []interface{}{"a", "b", "yes"},
+		},
+		{
+			name:           "path with index",
+			expr:           NotEqual(JSONPath("answers", "a", 2), "no"),
+			expectedSQL:    `t."answers"->?->>? != ?`,
+			expectedParams: []interface{}{"a", 2, "no"},
+		},
+		{
+			name:           "path is null",
+			expr:           IsNull(JSONPath("answers", "a")),
+			expectedSQL:    `t."answers"->>? IS NULL`,
+			expectedParams: []interface{}{"a"},
+		},
+		{
+			name:           "contains",
+			expr:           JSONContains("answers", map[string]interface{}{"a": []int{1, 2}}),
+			expectedSQL:    `t."answers" @> ?`,
+			expectedParams: []interface{}{`{"a":[1,2]}`},
+		},
+		{
+			name:           "has key",
+			expr:           JSONHasKey("answers", "a"),
+			expectedSQL:    `t."answers" \? ?`,
+			expectedParams: []interface{}{"a"},
+		},
+		{
+			name:           "has any keys",
+			expr:           JSONHasAnyKeys("answers", "a", "b"),
+			expectedSQL:    `t."answers" \?| ?`,
+			expectedParams: []interface{}{[]string{"a", "b"}},
+		},
+		{
+			name:           "has all keys",
+			expr:           JSONHasAllKeys("answers", "a", "b"),
+			expectedSQL:    `t."answers" \?& ?`,
+			expectedParams: []interface{}{[]string{"a", "b"}},
+		},
+		{
+			name:           "path matches",
+			expr:           JSONPathMatches("answers", "$.a ? (@ > 1)"),
+			expectedSQL:    `t."answers" @\? ?`,
+			expectedParams: []interface{}{"$.a ? (@ > 1)"},
+		},
+		{
+			name:           "nested in groups",
+			expr:           Or(JSONHasKey("answers", "a"), And(Equal(JSONPath("answers", "b"), "c"), JSONContains("answers", []string{"d"}))),
+			expectedSQL:    `(t."answers" \? ? OR (t."answers"->>? = ? AND t."answers" @> ?))`,
+			expectedParams: []interface{}{"a", "b", "c", `["d"]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+			assert.Equal(tt.expectedSQL, sql)
+			assert.Equal(tt.expectedParams, unwrapArrayParams(params))
+		})
+	}
+}
+
+func TestBuildCondition_JSONBErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := buildCondition("t", Equal(JSONPath("answers"), "a"))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", Equal(JSONPath("answers", 1.5), "a"))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", JSONContains("answers", make(chan int)))
+	assert.Error(err)
+}
+
+func TestApplyExpressionsToQuery_JSONB(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		Equal(JSONPath("answers", "a", 0), "it's"),
+		JSONContains("answers", map[string]string{"b": "'; DROP TABLE users; --"}),
+		JSONHasKey("answers", "c"),
+		JSONHasAnyKeys("answers", "d", "e"),
+		JSONHasAllKeys("answers", "f"),
+		JSONPathMatches("answers", "$.g ? (@ == \"h\")"),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `"expression_model"."answers"->'a'->>0 = 'it''s'`)
+	assert.Contains(string(b), `"expression_model"."answers" @> '{"b":"''; DROP TABLE users; --"}'`)
+	assert.Contains(string(b), `"expression_model"."answers" ? 'c'`)
+	assert.Contains(string(b), `"expression_model"."answers" ?| '{"d","e"}'`)
+	assert.Contains(string(b), `"expression_model"."answers" ?& '{"f"}'`)
+	assert.Contains(string(b), `"expression_model"."answers" @? '$.g ? (@ == "h")'`)
+}
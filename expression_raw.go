@@ -0,0 +1,87 @@
+package milo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Raw returns an expression for a raw SQL condition. Use it when the other expressions can't express a condition, so
+// finders still return mapped entities. sql may contain ? placeholders, which are bound to params in order, and
+// ?TableAlias, which is replaced with the alias of the table being queried. Escape a literal ? (e.g., the jsonb ?
+// operator) as \?. Other named placeholders aren't supported. The condition is wrapped in parentheses, so it can be
+// used in And and Or like any other expression.
+//
+// Never build sql from user input; pass user input as params.
+func Raw(sql string, params ...interface{}) Expression {
+	return Expression{
+		value:  sql,
+		t:      expressionTypeRaw,
+		params: params,
+	}
+}
+
+func (b *sqlBuilder) appendRaw(e Expression) error {
+	sql, ok := e.value.(string)
+	if !ok || len(strings.TrimSpace(sql)) == 0 {
+		return fmt.Errorf("raw expression must have sql")
+	}
+
+	b.sb.WriteByte('(')
+
+	var placeholders int
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		if c == '\\' && i+1 < len(sql) && sql[i+1] == '?' {
+			b.sb.WriteString(`\?`)
+			i++
+			continue
+		}
+
+		if c != '?' {
+			b.sb.WriteByte(c)
+			continue
+		}
+
+		j := i + 1
+		for j < len(sql) && isIdentByte(sql[j]) {
+			j++
+		}
+
+		name := sql[i+1 : j]
+
+		switch name {
+		case "":
+			b.sb.WriteByte('?')
+			placeholders++
+
+		case "TableAlias":
+			if len(b.alias) == 0 && j < len(sql) && sql[j] == '.' {
+				// Without an alias (see Expression.String), ?TableAlias.column is just column.
+				j++
+			}
+
+			b.sb.WriteString(b.alias)
+
+		default:
+			return fmt.Errorf("unsupported placeholder ?%s in raw expression %q", name, sql)
+		}
+
+		i = j - 1
+	}
+
+	b.sb.WriteByte(')')
+
+	if placeholders != len(e.params) {
+		return fmt.Errorf("raw expression %q has %d placeholders but %d params", sql, placeholders, len(e.params))
+	}
+
+	b.params = append(b.params, e.params...)
+
+	return nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,102 @@
+package milo
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaw(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := Raw("foo = ?", "bar")
+
+	assert.Equal(Expression{value: "foo = ?", t: expressionTypeRaw, params: []interface{}{"bar"}}, actual)
+}
+
+func TestBuildCondition_Raw(t *testing.T) {
+	tests := []struct {
+		name           string
+		expr           Expression
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:           "no params",
+			expr:           Raw("deleted_at IS NULL"),
+			expectedSQL:    "(deleted_at IS NULL)",
+			expectedParams: nil,
+		},
+		{
+			name:           "params",
+			expr:           Raw("age(birth_date) > ?::interval AND state = ?", "18 years", "MA"),
+			expectedSQL:    "(age(birth_date) > ?::interval AND state = ?)",
+			expectedParams: []interface{}{"18 years", "MA"},
+		},
+		{
+			name:           "table alias",
+			expr:           Raw("length(?TableAlias.name) = ?", 4),
+			expectedSQL:    "(length(t.name) = ?)",
+			expectedParams: []interface{}{4},
+		},
+		{
+			name:           "escaped placeholder",
+			expr:           Raw(`?TableAlias.answers \? ?`, "a"),
+			expectedSQL:    `(t.answers \? ?)`,
+			expectedParams: []interface{}{"a"},
+		},
+		{
+			name:           "in groups",
+			expr:           Or(Equal("a", 1), And(Raw("b = ? OR c = ?", 2, 3), Equal("d", 4))),
+			expectedSQL:    `(t."a" = ? OR ((b = ? OR c = ?) AND t."d" = ?))`,
+			expectedParams: []interface{}{1, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+			assert.Equal(tt.expectedSQL, sql)
+			assert.Equal(tt.expectedParams, params)
+		})
+	}
+}
+
+func TestBuildCondition_RawErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := buildCondition("t", Raw(""))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", Raw("a = ?"))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", Raw("a = ?", 1, 2))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", Raw("a = ?0", 1))
+	assert.Error(err)
+
+	_, _, err = buildCondition("t", Raw("a = ?name_first"))
+	assert.Error(err)
+}
+
+func TestApplyExpressionsToQuery_Raw(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		Or(Raw("length(?TableAlias.name_first) = ?", 4), Equal("name_last", "it's")),
+		Raw(`?TableAlias.name_last \? ?`, "a"),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE ((((length("expression_model".name_first) = 4) OR "expression_model"."name_last" = 'it''s') AND ("expression_model".name_last ? 'a')))`)
+}
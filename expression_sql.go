@@ -0,0 +1,262 @@
+package milo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/pkg/errors"
+)
+
+// sqlBuilder renders an Expression tree as a go-pg condition. Values are never written into the SQL; each one is
+// replaced by a ? placeholder and appended to params in order.
+type sqlBuilder struct {
+	alias  string
+	sb     strings.Builder
+	params []interface{}
+}
+
+// buildOrder renders the order expression e as an ORDER BY item for the table with the given alias.
+func buildOrder(alias string, e Expression) (string, []interface{}, error) {
+	b := &sqlBuilder{
+		alias: alias,
+	}
+
+	err := b.appendColumn(e.column)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b.sb.WriteByte(' ')
+	b.sb.WriteString(string(e.op))
+
+	return b.sb.String(), b.params, nil
+}
+
+// buildCondition renders e as a condition for the table with the given alias. Every group is wrapped in parentheses
+// so the precedence of the SQL always follows the shape of the tree.
+func buildCondition(alias string, e Expression) (string, []interface{}, error) {
+	b := &sqlBuilder{
+		alias: alias,
+	}
+
+	err := b.appendExpression(e)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return b.sb.String(), b.params, nil
+}
+
+// String renders e as SQL with its values inlined, without a table alias, e.g.,
+// ("name_first" = 'Jane' AND ("state" = 'MA' OR "state" = 'NY')). An order renders as ORDER BY "column" ASC. It's meant
+// for logs and debugging; finders always bind values as parameters.
+func (e Expression) String() string {
+	var sql string
+	var params []interface{}
+	var err error
+
+	if e.t == expressionTypeOrder {
+		sql, params, err = buildOrder("", e)
+		sql = "ORDER BY " + sql
+	} else {
+		sql, params, err = buildCondition("", e)
+	}
+
+	if err != nil {
+		return fmt.Sprintf("<invalid expression: %s>", err)
+	}
+
+	return string(orm.NewFormatter().FormatQuery(nil, sql, params...))
+}
+
+func (b *sqlBuilder) appendExpression(e Expression) error {
+	switch e.t {
+	case expressionTypeCondition:
+		return b.appendCondition(e)
+
+	case expressionTypeAnd:
+		return b.appendGroup(e.exprs, " AND ", "TRUE")
+
+	case expressionTypeOr:
+		return b.appendGroup(e.exprs, " OR ", "FALSE")
+
+	case expressionTypeOrder:
+		return fmt.Errorf("order by %v must be passed to a finder directly, not inside And or Or", e.column)
+
+	case expressionTypeRaw:
+		return b.appendRaw(e)
+
+	default:
+		return fmt.Errorf("unknown expressionType: %s", reflect.TypeOf(e.t).String())
+	}
+}
+
+func (b *sqlBuilder) appendGroup(exprs []Expression, sep string, empty string) error {
+	if len(exprs) == 0 {
+		b.sb.WriteString(empty)
+		return nil
+	}
+
+	b.sb.WriteByte('(')
+
+	for i, expr := range exprs {
+		if i > 0 {
+			b.sb.WriteString(sep)
+		}
+
+		err := b.appendExpression(expr)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.sb.WriteByte(')')
+
+	return nil
+}
+
+func (b *sqlBuilder) appendCondition(e Expression) error {
+	if len(e.op) == 0 {
+		return fmt.Errorf("missing operator for column %v", e.column)
+	}
+
+	switch e.op {
+	case OpIsNull, OpIsNotNull:
+		err := b.appendColumn(e.column)
+		if err != nil {
+			return err
+		}
+
+		b.sb.WriteByte(' ')
+		b.appendOp(e.op)
+
+	case OpAnyEqual:
+		b.sb.WriteString("? = ANY(")
+		b.params = append(b.params, e.value)
+
+		err := b.appendColumn(e.column)
+		if err != nil {
+			return err
+		}
+
+		b.sb.WriteByte(')')
+
+	case OpMatches:
+		return b.appendMatches(e)
+
+	default:
+		err := b.appendColumn(e.column)
+		if err != nil {
+			return err
+		}
+
+		b.sb.WriteByte(' ')
+		b.appendOp(e.op)
+
+		param, err := bindValue(e.op, e.value)
+		if err != nil {
+			return err
+		}
+
+		b.sb.WriteString(" ?")
+		b.params = append(b.params, param)
+	}
+
+	return nil
+}
+
+func (b *sqlBuilder) appendColumn(column interface{}) error {
+	switch c := column.(type) {
+	case string:
+		b.appendIdent(c)
+
+	case Column:
+		b.appendIdent(string(c))
+
+	case JSONPathColumn:
+		return b.appendJSONPathColumn(c)
+
+	case TSVectorColumn:
+		return b.appendTSVectorColumn(c)
+
+	case TSRankColumn:
+		return b.appendTSRankColumn(c)
+
+	case FuncColumn:
+		return b.appendFuncColumn(c)
+
+	default:
+		v := reflect.ValueOf(column)
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("unsupported column type %T", column)
+		}
+
+		b.appendIdent(v.String())
+	}
+
+	return nil
+}
+
+// isColumn returns true if arg is rendered as a column reference when it is a function argument.
+func isColumn(arg interface{}) bool {
+	switch arg.(type) {
+	case string, Column, JSONPathColumn, TSVectorColumn, TSRankColumn, FuncColumn:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// appendIdent writes the column name qualified with the table alias. name is always quoted, so it can't change the
+// meaning of the SQL around it.
+func (b *sqlBuilder) appendIdent(name string) {
+	if len(b.alias) > 0 {
+		b.sb.WriteString(b.alias)
+		b.sb.WriteByte('.')
+	}
+
+	b.sb.WriteString(quoteIdent(name))
+}
+
+func quoteIdent(name string) string {
+	name = strings.ReplaceAll(name, `"`, `""`)
+	// Escape ? so go-pg does not treat it as a placeholder.
+	name = strings.ReplaceAll(name, "?", `\?`)
+
+	return `"` + name + `"`
+}
+
+// appendOp writes op, escaping any ? so go-pg does not treat it as a placeholder.
+func (b *sqlBuilder) appendOp(op Op) {
+	b.sb.WriteString(strings.ReplaceAll(string(op), "?", `\?`))
+}
+
+// bindValue returns the param bound for the value of a condition with operator op.
+func bindValue(op Op, value interface{}) (interface{}, error) {
+	switch op {
+	case OpContains:
+		// Array containment values are already wrapped with pg.Array. Anything else is a jsonb document.
+		if _, ok := value.(*types.Array); ok {
+			return value, nil
+		}
+
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding %T as json", value)
+		}
+
+		return string(b), nil
+
+	case OpHasAnyKeys, OpHasAllKeys:
+		return pg.Array(value), nil
+
+	default:
+		return value, nil
+	}
+}
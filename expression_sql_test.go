@@ -0,0 +1,305 @@
+package milo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type expressionModel struct {
+	tableName struct{} `pg:"expressions"`
+
+	ID string `pg:"id"`
+
+	NameFirst string `pg:"name_first"`
+	NameLast  string `pg:"name_last"`
+	Email     string `pg:"email"`
+
+	Answers map[string]interface{} `pg:"answers,type:jsonb"`
+	Tags    []string               `pg:"tags,array"`
+	Codes   []int                  `pg:"codes,array"`
+	Search  string                 `pg:"search,type:tsvector"`
+
+	CreatedAt time.Time `pg:"created_at"`
+}
+
+var _ Model = (*expressionModel)(nil)
+
+// expressionModel is its own entity.
+func (e *expressionModel) FromEntity(entity interface{}) error {
+	*e = *entity.(*expressionModel)
+
+	return nil
+}
+
+func (e *expressionModel) ToEntity() (interface{}, error) {
+	entity := *e
+
+	return &entity, nil
+}
+
+func TestBuildCondition(t *testing.T) {
+	a := Equal("a", 1)
+	b := Equal("b", 2)
+	c := Equal("c", 3)
+	d := Equal("d", 4)
+
+	tests := []struct {
+		name           string
+		expr           Expression
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:           "condition",
+			expr:           a,
+			expectedSQL:    `t."a" = ?`,
+			expectedParams: []interface{}{1},
+		},
+		{
+			name:           "is null",
+			expr:           IsNull("a"),
+			expectedSQL:    `t."a" IS NULL`,
+			expectedParams: nil,
+		},
+		{
+			name:           "is not null",
+			expr:           IsNotNull("a"),
+			expectedSQL:    `t."a" IS NOT NULL`,
+			expectedParams: nil,
+		},
+		{
+			name:           "empty and",
+			expr:           And(),
+			expectedSQL:    "TRUE",
+			expectedParams: nil,
+		},
+		{
+			name:           "empty or",
+			expr:           Or(),
+			expectedSQL:    "FALSE",
+			expectedParams: nil,
+		},
+		{
+			name:           "and with one child",
+			expr:           And(a),
+			expectedSQL:    `(t."a" = ?)`,
+			expectedParams: []interface{}{1},
+		},
+		{
+			name:           "or with one child",
+			expr:           Or(a),
+			expectedSQL:    `(t."a" = ?)`,
+			expectedParams: []interface{}{1},
+		},
+		{
+			name:           "and",
+			expr:           And(a, b, c),
+			expectedSQL:    `(t."a" = ? AND t."b" = ? AND t."c" = ?)`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "or",
+			expr:           Or(a, b, c),
+			expectedSQL:    `(t."a" = ? OR t."b" = ? OR t."c" = ?)`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "and containing or",
+			expr:           And(a, Or(b, c)),
+			expectedSQL:    `(t."a" = ? AND (t."b" = ? OR t."c" = ?))`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "or containing and",
+			expr:           Or(a, And(b, c)),
+			expectedSQL:    `(t."a" = ? OR (t."b" = ? AND t."c" = ?))`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "or first in and",
+			expr:           And(Or(a, b), c),
+			expectedSQL:    `((t."a" = ? OR t."b" = ?) AND t."c" = ?)`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "and first in or",
+			expr:           Or(And(a, b), c),
+			expectedSQL:    `((t."a" = ? AND t."b" = ?) OR t."c" = ?)`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "and of ors",
+			expr:           And(Or(a, b), Or(c, d)),
+			expectedSQL:    `((t."a" = ? OR t."b" = ?) AND (t."c" = ? OR t."d" = ?))`,
+			expectedParams: []interface{}{1, 2, 3, 4},
+		},
+		{
+			name:           "or of ands",
+			expr:           Or(And(a, b), And(c, d)),
+			expectedSQL:    `((t."a" = ? AND t."b" = ?) OR (t."c" = ? AND t."d" = ?))`,
+			expectedParams: []interface{}{1, 2, 3, 4},
+		},
+		{
+			name:           "and nested in and",
+			expr:           And(a, And(b, c)),
+			expectedSQL:    `(t."a" = ? AND (t."b" = ? AND t."c" = ?))`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "or nested in or",
+			expr:           Or(a, Or(b, c)),
+			expectedSQL:    `(t."a" = ? OR (t."b" = ? OR t."c" = ?))`,
+			expectedParams: []interface{}{1, 2, 3},
+		},
+		{
+			name:           "deep nesting",
+			expr:           Or(a, And(b, Or(c, And(d, IsNull("e"))))),
+			expectedSQL:    `(t."a" = ? OR (t."b" = ? AND (t."c" = ? OR (t."d" = ? AND t."e" IS NULL))))`,
+			expectedParams: []interface{}{1, 2, 3, 4},
+		},
+		{
+			name:           "empty groups nested",
+			expr:           Or(And(), And(a, Or())),
+			expectedSQL:    `(TRUE OR (t."a" = ? AND FALSE))`,
+			expectedParams: []interface{}{1},
+		},
+		{
+			name:           "all operators",
+			expr:           And(Equal("a", 1), NotEqual("b", 2), Gt("c", 3), Lt("d", 4), Gte("e", 5), Lte("f", 6)),
+			expectedSQL:    `(t."a" = ? AND t."b" != ? AND t."c" > ? AND t."d" < ? AND t."e" >= ? AND t."f" <= ?)`,
+			expectedParams: []interface{}{1, 2, 3, 4, 5, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := buildCondition("t", tt.expr)
+			assert.NoError(err)
+			assert.Equal(tt.expectedSQL, sql)
+			assert.Equal(tt.expectedParams, params)
+		})
+	}
+}
+
+func TestBuildCondition_DoesNotMutateChildren(t *testing.T) {
+	assert := assert.New(t)
+
+	or := Or(Equal("b", 2), Equal("c", 3))
+
+	sql, _, err := buildCondition("t", And(Equal("a", 1), or))
+	assert.NoError(err)
+	assert.Equal(`(t."a" = ? AND (t."b" = ? OR t."c" = ?))`, sql)
+
+	sql, _, err = buildCondition("t", or)
+	assert.NoError(err)
+	assert.Equal(`(t."b" = ? OR t."c" = ?)`, sql)
+}
+
+func TestBuildCondition_MissingOperator(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := buildCondition("t", And(Expression{column: "a"}))
+	assert.Error(err)
+}
+
+func TestApplyExpressionsToQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{
+		Equal("name_first", "John"),
+		Or(Equal("name_last", "Smith"), And(Equal("name_last", "Doe"), IsNotNull("email"))),
+	}, query)
+	assert.NoError(err)
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	assert.NoError(err)
+	assert.Contains(string(b), `WHERE (("expression_model"."name_first" = 'John' AND ("expression_model"."name_last" = 'Smith' OR ("expression_model"."name_last" = 'Doe' AND "expression_model"."email" IS NOT NULL))))`)
+}
+
+// unwrapArrayParams replaces pg.Array params with the slices they wrap so params can be compared with assert.Equal.
+func unwrapArrayParams(params []interface{}) []interface{} {
+	for i, param := range params {
+		if array, ok := param.(*types.Array); ok {
+			params[i] = array.Value()
+		}
+	}
+
+	return params
+}
+
+func TestExpression_String(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     Expression
+		expected string
+	}{
+		{
+			name:     "condition",
+			expr:     Equal("name_first", "Jane"),
+			expected: `"name_first" = 'Jane'`,
+		},
+		{
+			name:     "nested",
+			expr:     And(Equal("name_first", "Jane"), Or(Equal("state", "MA"), Gte("age", 65), IsNull("state"))),
+			expected: `("name_first" = 'Jane' AND ("state" = 'MA' OR "age" >= 65 OR "state" IS NULL))`,
+		},
+		{
+			name:     "empty groups",
+			expr:     Or(And(), Or()),
+			expected: `(TRUE OR FALSE)`,
+		},
+		{
+			name:     "quotes",
+			expr:     NotEqual("name_last", "O'Brien"),
+			expected: `"name_last" != 'O''Brien'`,
+		},
+		{
+			name:     "time",
+			expr:     Lt("created_at", day),
+			expected: `"created_at" < '2021-06-01 00:00:00+00:00:00'`,
+		},
+		{
+			name:     "jsonb",
+			expr:     And(JSONHasKey("answers", "a"), Equal(JSONPath("answers", "b", 0), "c")),
+			expected: `("answers" ? 'a' AND "answers"->'b'->>0 = 'c')`,
+		},
+		{
+			name:     "array",
+			expr:     ArrayOverlaps("tags", []string{"a", "b"}),
+			expected: `"tags" && '{"a","b"}'`,
+		},
+		{
+			name:     "raw",
+			expr:     Raw("length(?TableAlias.name_first) > ?", 3),
+			expected: `(length(name_first) > 3)`,
+		},
+		{
+			name:     "order",
+			expr:     OrderByDesc(Lower("name_last")),
+			expected: `ORDER BY lower("name_last") DESC`,
+		},
+		{
+			name:     "invalid",
+			expr:     Equal(1, "a"),
+			expected: `<invalid expression: unsupported column type int>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tt.expected, tt.expr.String())
+		})
+	}
+}
@@ -14,19 +14,17 @@ func TestAnd(t *testing.T) {
 			column: "foo",
 			op:     OpEqual,
 			value:  "bar",
-			t:      expressionTypeAnd,
 		},
 		{
 			column: "bar",
 			op:     OpNotEqual,
 			value:  "baz",
-			t:      expressionTypeAnd,
 		},
 	}
 
 	actual := And(exprs...)
 
-	assert.Equal(Expression{exprs: exprs}, actual)
+	assert.Equal(Expression{t: expressionTypeAnd, exprs: exprs}, actual)
 }
 
 func TestOr(t *testing.T) {
@@ -37,19 +35,17 @@ func TestOr(t *testing.T) {
 			column: "foo",
 			op:     OpEqual,
 			value:  "bar",
-			t:      expressionTypeOr,
 		},
 		{
 			column: "bar",
 			op:     OpNotEqual,
 			value:  "baz",
-			t:      expressionTypeOr,
 		},
 	}
 
 	actual := Or(exprs...)
 
-	assert.Equal(Expression{exprs: exprs}, actual)
+	assert.Equal(Expression{t: expressionTypeOr, exprs: exprs}, actual)
 }
 
 func TestEqual(t *testing.T) {
@@ -57,7 +53,7 @@ func TestEqual(t *testing.T) {
 
 	actual := Equal("foo", "bar")
 
-	assert.Equal(Expression{column: "foo", op: OpEqual, value: "bar", t: expressionTypeAnd}, actual)
+	assert.Equal(Expression{column: "foo", op: OpEqual, value: "bar"}, actual)
 }
 
 func TestNotEqual(t *testing.T) {
@@ -65,7 +61,7 @@ func TestNotEqual(t *testing.T) {
 
 	actual := NotEqual("foo", "bar")
 
-	assert.Equal(Expression{column: "foo", op: OpNotEqual, value: "bar", t: expressionTypeAnd}, actual)
+	assert.Equal(Expression{column: "foo", op: OpNotEqual, value: "bar"}, actual)
 }
 
 func TestGt(t *testing.T) {
@@ -73,7 +69,7 @@ func TestGt(t *testing.T) {
 
 	actual := Gt("foo", "bar")
 
-	assert.Equal(Expression{column: "foo", op: OpGt, value: "bar", t: expressionTypeAnd}, actual)
+	assert.Equal(Expression{column: "foo", op: OpGt, value: "bar"}, actual)
 }
 
 func TestLt(t *testing.T) {
@@ -81,7 +77,7 @@ func TestLt(t *testing.T) {
 
 	actual := Lt("foo", "bar")
 
-	assert.Equal(Expression{column: "foo", op: OpLt, value: "bar", t: expressionTypeAnd}, actual)
+	assert.Equal(Expression{column: "foo", op: OpLt, value: "bar"}, actual)
 }
 
 func TestGte(t *testing.T) {
@@ -89,7 +85,7 @@ func TestGte(t *testing.T) {
 
 	actual := Gte("foo", "bar")
 
-	assert.Equal(Expression{column: "foo", op: OpGte, value: "bar", t: expressionTypeAnd}, actual)
+	assert.Equal(Expression{column: "foo", op: OpGte, value: "bar"}, actual)
 }
 
 func TestLte(t *testing.T) {
@@ -97,5 +93,21 @@ func TestLte(t *testing.T) {
 
 	actual := Lte("foo", "bar")
 
-	assert.Equal(Expression{column: "foo", op: OpLte, value: "bar", t: expressionTypeAnd}, actual)
+	assert.Equal(Expression{column: "foo", op: OpLte, value: "bar"}, actual)
+}
+
+func TestOrderBy(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := OrderBy("foo")
+
+	assert.Equal(Expression{column: "foo", op: orderAsc, t: expressionTypeOrder}, actual)
+}
+
+func TestOrderByDesc(t *testing.T) {
+	assert := assert.New(t)
+
+	actual := OrderByDesc("foo")
+
+	assert.Equal(Expression{column: "foo", op: orderDesc, t: expressionTypeOrder}, actual)
 }
@@ -0,0 +1,165 @@
+package milo
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// validateExpressions returns an *UnknownColumnError for the first column referenced by exprs that isn't a column of
+// table. Raw expressions aren't validated.
+func validateExpressions(table *orm.Table, exprs []Expression) error {
+	for _, e := range exprs {
+		err := validateColumn(table, e.column)
+		if err != nil {
+			return err
+		}
+
+		err = validateExpressions(table, e.exprs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateColumn(table *orm.Table, column interface{}) error {
+	switch c := column.(type) {
+	case nil:
+		return nil
+
+	case string:
+		return validateColumnName(table, c)
+
+	case Column:
+		return validateColumnName(table, string(c))
+
+	case JSONPathColumn:
+		return validateColumn(table, c.column)
+
+	case TSVectorColumn:
+		for _, column := range c.columns {
+			err := validateColumn(table, column)
+			if err != nil {
+				return err
+			}
+		}
+
+	case TSRankColumn:
+		return validateColumn(table, c.match.column)
+
+	case FuncColumn:
+		for _, arg := range c.args {
+			if !isColumn(arg) {
+				continue
+			}
+
+			err := validateColumn(table, arg)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		v := reflect.ValueOf(column)
+		if v.Kind() == reflect.String {
+			return validateColumnName(table, v.String())
+		}
+	}
+
+	return nil
+}
+
+func validateColumnName(table *orm.Table, name string) error {
+	if table.HasField(name) {
+		return nil
+	}
+
+	return &UnknownColumnError{
+		Model:       table.Type.String(),
+		Column:      name,
+		Suggestions: suggestColumns(table, name),
+	}
+}
+
+// suggestColumns returns up to 3 columns of table whose names are close to name, closest first.
+func suggestColumns(table *orm.Table, name string) []string {
+	type suggestion struct {
+		column   string
+		distance int
+	}
+
+	maxDistance := len(name) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	var suggestions []suggestion
+
+	for _, field := range table.Fields {
+		distance := levenshtein(name, field.SQLName)
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{
+				column:   field.SQLName,
+				distance: distance,
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+
+		return suggestions[i].column < suggestions[j].column
+	})
+
+	var columns []string
+
+	for i := 0; i < len(suggestions) && i < 3; i++ {
+		columns = append(columns, suggestions[i].column)
+	}
+
+	return columns
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(first int, rest ...int) int {
+	m := first
+
+	for _, i := range rest {
+		if i < m {
+			m = i
+		}
+	}
+
+	return m
+}
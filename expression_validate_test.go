@@ -0,0 +1,127 @@
+package milo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExpressions(t *testing.T) {
+	table := orm.GetTable(reflect.TypeOf(expressionModel{}))
+
+	tests := []struct {
+		name     string
+		exprs    []Expression
+		expected *UnknownColumnError
+	}{
+		{
+			name:  "valid",
+			exprs: []Expression{Equal("name_first", "a"), Or(IsNull("email"), And(Gt("created_at", 1))), OrderBy("name_last")},
+		},
+		{
+			name:  "valid column references",
+			exprs: []Expression{Equal(Lower(Coalesce("email", Literal("a"))), "a"), Equal(JSONPath("answers", "a"), "b"), Matches([]interface{}{"name_first"}, "a", ""), MatchesVector("search", "a", ""), OrderByRank(MatchesVector("search", "a", ""))},
+		},
+		{
+			name:  "raw",
+			exprs: []Expression{Raw("name_frist = ?", "a")},
+		},
+		{
+			name:     "typo",
+			exprs:    []Expression{Equal("name_frist", "a")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "name_frist", Suggestions: []string{"name_first", "name_last"}},
+		},
+		{
+			name:     "no suggestions",
+			exprs:    []Expression{Equal("zzzzzzzz", "a")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "zzzzzzzz"},
+		},
+		{
+			name:     "nested",
+			exprs:    []Expression{Or(Equal("name_first", "a"), And(Equal("emial", "b")))},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "emial", Suggestions: []string{"email"}},
+		},
+		{
+			name:     "order",
+			exprs:    []Expression{OrderBy("creatd_at")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "creatd_at", Suggestions: []string{"created_at"}},
+		},
+		{
+			name:     "function",
+			exprs:    []Expression{Equal(Lower(Coalesce("emails", Literal("a"))), "a")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "emails", Suggestions: []string{"email"}},
+		},
+		{
+			name:     "json path",
+			exprs:    []Expression{Equal(JSONPath("answer", "a"), "b")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "answer", Suggestions: []string{"answers"}},
+		},
+		{
+			name:     "full-text search",
+			exprs:    []Expression{Matches([]interface{}{"name_first", "nme_last"}, "a", "")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "nme_last", Suggestions: []string{"name_last"}},
+		},
+		{
+			name:     "rank",
+			exprs:    []Expression{OrderByRank(MatchesVector("serch", "a", ""))},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "serch", Suggestions: []string{"search"}},
+		},
+		{
+			name:     "relation",
+			exprs:    []Expression{Equal("Profile", "a")},
+			expected: &UnknownColumnError{Model: "milo.expressionModel", Column: "Profile"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := validateExpressions(table, tt.exprs)
+
+			if tt.expected == nil {
+				assert.NoError(err)
+				return
+			}
+
+			assert.Equal(tt.expected, err)
+		})
+	}
+}
+
+func TestUnknownColumnError_Error(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &UnknownColumnError{Model: "storage.customer", Column: "name_frist", Suggestions: []string{"name_first", "name_last"}}
+	assert.Equal(`unknown column "name_frist" for model storage.customer (did you mean name_first, name_last?)`, err.Error())
+
+	err = &UnknownColumnError{Model: "storage.customer", Column: "foo"}
+	assert.Equal(`unknown column "foo" for model storage.customer`, err.Error())
+}
+
+func TestApplyExpressionsToQuery_UnknownColumn(t *testing.T) {
+	assert := assert.New(t)
+
+	query := orm.NewQuery(nil, &expressionModel{})
+
+	err := applyExpressionsToQuery([]Expression{Equal("name_first", "a"), Equal("name_frist", "a")}, query)
+	assert.Error(err)
+
+	var unknownColumnErr *UnknownColumnError
+	assert.True(errors.As(err, &unknownColumnErr))
+	assert.Equal("name_frist", unknownColumnErr.Column)
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, levenshtein("", ""))
+	assert.Equal(3, levenshtein("abc", ""))
+	assert.Equal(3, levenshtein("", "abc"))
+	assert.Equal(0, levenshtein("name_first", "name_first"))
+	assert.Equal(2, levenshtein("name_frist", "name_first"))
+	assert.Equal(3, levenshtein("kitten", "sitting"))
+}
@@ -0,0 +1,814 @@
+package milo
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// FilterParser parses filters from URL query parameters into Expressions for one entity type. Filters may only
+// reference the columns the parser was created with, and values are converted to the Go type of the model field they
+// are compared to.
+//
+// A filter is a boolean expression of comparisons joined with and and or, grouped with parentheses. and binds tighter
+// than or. Keywords are case-insensitive.
+//
+//	name_first eq 'Jane' and (state in ('MA', 'NY') or age ge 65)
+//
+// The comparisons are eq, ne, gt, ge, lt and le followed by a value, in and not in followed by a parenthesized list
+// of values, is null and is not null, and has followed by a value, which matches array columns that contain the value.
+// Values are strings in single quotes (a quote inside a string is written twice), numbers, true and false.
+type FilterParser struct {
+	model   string
+	columns map[string]*orm.Field
+}
+
+// FilterError is returned when a filter can't be parsed. Position is the 1-based byte offset of the error in the
+// filter.
+type FilterError struct {
+	Param    string
+	Position int
+	Message  string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter %s at position %d: %s", e.Param, e.Position, e.Message)
+}
+
+// NewFilterParser returns a parser for filters on entity, which must be a pointer to an entity type in
+// entityModelMap. columns are the only columns filters may reference and must be columns of the entity's model.
+func NewFilterParser(entityModelMap EntityModelMap, entity interface{}, columns ...string) (*FilterParser, error) {
+	entityType := reflect.TypeOf(entity)
+
+	modelType, ok := entityModelMap[entityType]
+	if !ok {
+		return nil, fmt.Errorf("unable to find model type for entity type %s", entityType)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns allowed for entity type %s", entityType)
+	}
+
+	table := orm.GetTable(modelType.Elem())
+
+	p := &FilterParser{
+		model:   table.Type.String(),
+		columns: make(map[string]*orm.Field, len(columns)),
+	}
+
+	for _, column := range columns {
+		field, ok := table.FieldsMap[column]
+		if !ok {
+			return nil, &UnknownColumnError{
+				Model:       p.model,
+				Column:      column,
+				Suggestions: suggestColumns(table, column),
+			}
+		}
+
+		p.columns[column] = field
+	}
+
+	return p, nil
+}
+
+// Parse parses filter into an Expression.
+func (p *FilterParser) Parse(filter string) (Expression, error) {
+	return p.parse("filter", filter)
+}
+
+// ParseQuery parses the filter parameter and every parameter of the form field[op]=value in values into Expressions,
+// which are combined with AND when passed to a finder. The ops of the bracket form are eq, ne, gt, ge, lt, le, has,
+// in and nin, which take a comma separated list of values, and null, which takes true or false. Other parameters,
+// including bracketed ones whose field isn't one of the parser's columns (e.g., page[size] for pagination), are
+// ignored.
+func (p *FilterParser) ParseQuery(values url.Values) ([]Expression, error) {
+	var exprs []Expression
+
+	for _, filter := range values["filter"] {
+		expr, err := p.parse("filter", filter)
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, expr)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	// Sort the keys so the expressions (and any error) don't depend on map order.
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		m := filterParamRegexp.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+
+		if _, ok := p.columns[m[1]]; !ok {
+			continue
+		}
+
+		for _, value := range values[key] {
+			expr, err := p.parseParam(key, m[1], strings.ToLower(m[2]), value)
+			if err != nil {
+				return nil, err
+			}
+
+			exprs = append(exprs, expr)
+		}
+	}
+
+	return exprs, nil
+}
+
+var filterParamRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\[([A-Za-z]+)\]$`)
+
+func (p *FilterParser) parseParam(key string, column string, op string, value string) (Expression, error) {
+	field := p.columns[column]
+
+	lit := filterLiteral{
+		kind: filterLiteralText,
+		text: value,
+		pos:  1,
+	}
+
+	fail := func(err error) (Expression, error) {
+		return Expression{}, &FilterError{Param: key, Position: 1, Message: err.Error()}
+	}
+
+	switch op {
+	case "in", "nin":
+		var lits []filterLiteral
+
+		for _, text := range strings.Split(value, ",") {
+			lits = append(lits, filterLiteral{kind: filterLiteralText, text: strings.TrimSpace(text), pos: 1})
+		}
+
+		expr, err := inExpression(field, op == "nin", lits)
+		if err != nil {
+			return fail(err)
+		}
+
+		return expr, nil
+
+	case "null":
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return fail(fmt.Errorf("expected true or false, got %q", value))
+		}
+
+		if isNull {
+			return IsNull(column), nil
+		}
+
+		return IsNotNull(column), nil
+
+	default:
+		expr, err := compareExpression(field, op, lit)
+		if err != nil {
+			return fail(err)
+		}
+
+		return expr, nil
+	}
+}
+
+func (p *FilterParser) parse(param string, filter string) (Expression, error) {
+	l := &filterLexer{
+		param: param,
+		input: filter,
+	}
+
+	tokens, err := l.lex()
+	if err != nil {
+		return Expression{}, err
+	}
+
+	fp := &filterParser{
+		FilterParser: p,
+		param:        param,
+		tokens:       tokens,
+	}
+
+	expr, err := fp.parseOr()
+	if err != nil {
+		return Expression{}, err
+	}
+
+	if tok := fp.peek(); tok.kind != filterTokenEOF {
+		return Expression{}, fp.errorf(tok, "expected and, or or the end of the filter, got %s", tok)
+	}
+
+	return expr, nil
+}
+
+func (p *FilterParser) unknownColumn(param string, pos int, column string) error {
+	var allowed []string
+	for name := range p.columns {
+		allowed = append(allowed, name)
+	}
+
+	sort.Strings(allowed)
+
+	return &FilterError{
+		Param:    param,
+		Position: pos,
+		Message:  fmt.Sprintf("unknown column %q (allowed columns are %s)", column, strings.Join(allowed, ", ")),
+	}
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenEOF filterTokenKind = iota
+	filterTokenIdent
+	filterTokenString
+	filterTokenNumber
+	filterTokenLParen
+	filterTokenRParen
+	filterTokenComma
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case filterTokenEOF:
+		return "the end of the filter"
+	case filterTokenString:
+		return fmt.Sprintf("'%s'", strings.ReplaceAll(t.text, "'", "''"))
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// is returns true if t is the keyword kw.
+func (t filterToken) is(kw string) bool {
+	return t.kind == filterTokenIdent && strings.EqualFold(t.text, kw)
+}
+
+type filterLexer struct {
+	param string
+	input string
+	pos   int
+}
+
+func (l *filterLexer) lex() ([]filterToken, error) {
+	var tokens []filterToken
+
+	for {
+		for l.pos < len(l.input) && isFilterSpace(l.input[l.pos]) {
+			l.pos++
+		}
+
+		if l.pos >= len(l.input) {
+			return append(tokens, filterToken{kind: filterTokenEOF, pos: l.pos + 1}), nil
+		}
+
+		start := l.pos
+		c := l.input[l.pos]
+
+		switch {
+		case c == '(':
+			l.pos++
+			tokens = append(tokens, filterToken{kind: filterTokenLParen, text: "(", pos: start + 1})
+
+		case c == ')':
+			l.pos++
+			tokens = append(tokens, filterToken{kind: filterTokenRParen, text: ")", pos: start + 1})
+
+		case c == ',':
+			l.pos++
+			tokens = append(tokens, filterToken{kind: filterTokenComma, text: ",", pos: start + 1})
+
+		case c == '\'':
+			s, err := l.lexString()
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, filterToken{kind: filterTokenString, text: s, pos: start + 1})
+
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			l.pos++
+			for l.pos < len(l.input) && isFilterNumberByte(l.input[l.pos]) {
+				l.pos++
+			}
+
+			tokens = append(tokens, filterToken{kind: filterTokenNumber, text: l.input[start:l.pos], pos: start + 1})
+
+		case isIdentByte(c) && !(c >= '0' && c <= '9'):
+			for l.pos < len(l.input) && isIdentByte(l.input[l.pos]) {
+				l.pos++
+			}
+
+			tokens = append(tokens, filterToken{kind: filterTokenIdent, text: l.input[start:l.pos], pos: start + 1})
+
+		default:
+			return nil, &FilterError{Param: l.param, Position: start + 1, Message: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+}
+
+func (l *filterLexer) lexString() (string, error) {
+	start := l.pos
+	l.pos++
+
+	var sb strings.Builder
+
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		l.pos++
+
+		if c != '\'' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		if l.pos < len(l.input) && l.input[l.pos] == '\'' {
+			sb.WriteByte('\'')
+			l.pos++
+
+			continue
+		}
+
+		return sb.String(), nil
+	}
+
+	return "", &FilterError{Param: l.param, Position: start + 1, Message: "unterminated string"}
+}
+
+func isFilterSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isFilterNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '-' || c == '+'
+}
+
+// filterParser is a recursive descent parser over the tokens of one filter.
+type filterParser struct {
+	*FilterParser
+
+	param  string
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+
+	if tok.kind != filterTokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	return &FilterError{
+		Param:    p.param,
+		Position: tok.pos,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// parseOr parses term { or term }.
+func (p *filterParser) parseOr() (Expression, error) {
+	expr, err := p.parseAnd()
+	if err != nil {
+		return Expression{}, err
+	}
+
+	exprs := []Expression{expr}
+
+	for p.peek().is("or") {
+		p.next()
+
+		expr, err := p.parseAnd()
+		if err != nil {
+			return Expression{}, err
+		}
+
+		exprs = append(exprs, expr)
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return Or(exprs...), nil
+}
+
+// parseAnd parses factor { and factor }.
+func (p *filterParser) parseAnd() (Expression, error) {
+	expr, err := p.parseFactor()
+	if err != nil {
+		return Expression{}, err
+	}
+
+	exprs := []Expression{expr}
+
+	for p.peek().is("and") {
+		p.next()
+
+		expr, err := p.parseFactor()
+		if err != nil {
+			return Expression{}, err
+		}
+
+		exprs = append(exprs, expr)
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return And(exprs...), nil
+}
+
+// parseFactor parses ( expr ) or a comparison.
+func (p *filterParser) parseFactor() (Expression, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case filterTokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return Expression{}, err
+		}
+
+		if closing := p.next(); closing.kind != filterTokenRParen {
+			return Expression{}, p.errorf(closing, "expected \")\" to close \"(\" at position %d, got %s", tok.pos, closing)
+		}
+
+		return expr, nil
+
+	case filterTokenIdent:
+		return p.parseComparison(tok)
+
+	default:
+		return Expression{}, p.errorf(tok, "expected a column or \"(\", got %s", tok)
+	}
+}
+
+func (p *filterParser) parseComparison(columnTok filterToken) (Expression, error) {
+	field, ok := p.columns[columnTok.text]
+	if !ok {
+		return Expression{}, p.unknownColumn(p.param, columnTok.pos, columnTok.text)
+	}
+
+	opTok := p.next()
+
+	switch {
+	case opTok.is("is"):
+		tok := p.next()
+
+		if tok.is("null") {
+			return IsNull(field.SQLName), nil
+		}
+
+		if tok.is("not") {
+			if tok := p.next(); !tok.is("null") {
+				return Expression{}, p.errorf(tok, "expected null, got %s", tok)
+			}
+
+			return IsNotNull(field.SQLName), nil
+		}
+
+		return Expression{}, p.errorf(tok, "expected null or not null, got %s", tok)
+
+	case opTok.is("in"), opTok.is("not"):
+		not := opTok.is("not")
+
+		if not {
+			if tok := p.next(); !tok.is("in") {
+				return Expression{}, p.errorf(tok, "expected in, got %s", tok)
+			}
+		}
+
+		lits, err := p.parseList()
+		if err != nil {
+			return Expression{}, err
+		}
+
+		expr, err := inExpression(field, not, lits)
+		if err != nil {
+			return Expression{}, p.literalError(err)
+		}
+
+		return expr, nil
+
+	case opTok.kind == filterTokenIdent:
+		op := strings.ToLower(opTok.text)
+		if _, ok := filterOps[op]; !ok && op != "has" {
+			return Expression{}, p.errorf(opTok, "unknown operator %q", opTok.text)
+		}
+
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return Expression{}, err
+		}
+
+		expr, err := compareExpression(field, op, lit)
+		if err != nil {
+			return Expression{}, p.literalError(err)
+		}
+
+		return expr, nil
+
+	default:
+		return Expression{}, p.errorf(opTok, "expected an operator after column %q, got %s", columnTok.text, opTok)
+	}
+}
+
+// parseList parses ( literal { , literal } ).
+func (p *filterParser) parseList() ([]filterLiteral, error) {
+	if tok := p.next(); tok.kind != filterTokenLParen {
+		return nil, p.errorf(tok, "expected \"(\", got %s", tok)
+	}
+
+	var lits []filterLiteral
+
+	for {
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+
+		lits = append(lits, lit)
+
+		tok := p.next()
+		if tok.kind == filterTokenRParen {
+			return lits, nil
+		}
+
+		if tok.kind != filterTokenComma {
+			return nil, p.errorf(tok, "expected \",\" or \")\", got %s", tok)
+		}
+	}
+}
+
+func (p *filterParser) parseLiteral() (filterLiteral, error) {
+	tok := p.next()
+
+	switch {
+	case tok.kind == filterTokenString:
+		return filterLiteral{kind: filterLiteralString, text: tok.text, pos: tok.pos}, nil
+
+	case tok.kind == filterTokenNumber:
+		return filterLiteral{kind: filterLiteralNumber, text: tok.text, pos: tok.pos}, nil
+
+	case tok.is("true"), tok.is("false"):
+		return filterLiteral{kind: filterLiteralBool, text: strings.ToLower(tok.text), pos: tok.pos}, nil
+
+	default:
+		return filterLiteral{}, p.errorf(tok, "expected a value, got %s", tok)
+	}
+}
+
+func (p *filterParser) literalError(err error) error {
+	if litErr, ok := err.(*filterLiteralError); ok {
+		return &FilterError{Param: p.param, Position: litErr.lit.pos, Message: litErr.msg}
+	}
+
+	return err
+}
+
+type filterLiteralKind int
+
+const (
+	filterLiteralString filterLiteralKind = iota
+	filterLiteralNumber
+	filterLiteralBool
+	// filterLiteralText is the untyped value of a field[op]=value parameter, which is converted to any type.
+	filterLiteralText
+)
+
+type filterLiteral struct {
+	kind filterLiteralKind
+	text string
+	pos  int
+}
+
+type filterLiteralError struct {
+	lit filterLiteral
+	msg string
+}
+
+func (e *filterLiteralError) Error() string {
+	return e.msg
+}
+
+var filterOps = map[string]func(column interface{}, value interface{}) Expression{
+	"eq": Equal,
+	"ne": NotEqual,
+	"gt": Gt,
+	"ge": Gte,
+	"lt": Lt,
+	"le": Lte,
+}
+
+func compareExpression(field *orm.Field, op string, lit filterLiteral) (Expression, error) {
+	if op == "has" {
+		t := indirectType(field.Type)
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return Expression{}, &filterLiteralError{lit: lit, msg: fmt.Sprintf("has requires an array column, %q is %s", field.SQLName, t)}
+		}
+
+		value, err := coerceFilterLiteral(field.SQLName, t.Elem(), lit)
+		if err != nil {
+			return Expression{}, err
+		}
+
+		return AnyEqual(field.SQLName, value), nil
+	}
+
+	fn, ok := filterOps[op]
+	if !ok {
+		return Expression{}, &filterLiteralError{lit: lit, msg: fmt.Sprintf("unknown operator %q", op)}
+	}
+
+	value, err := coerceFilterLiteral(field.SQLName, field.Type, lit)
+	if err != nil {
+		return Expression{}, err
+	}
+
+	return fn(field.SQLName, value), nil
+}
+
+// inExpression returns column = a OR column = b ... or, if not is true, column != a AND column != b ...
+func inExpression(field *orm.Field, not bool, lits []filterLiteral) (Expression, error) {
+	exprs := make([]Expression, len(lits))
+
+	for i, lit := range lits {
+		value, err := coerceFilterLiteral(field.SQLName, field.Type, lit)
+		if err != nil {
+			return Expression{}, err
+		}
+
+		if not {
+			exprs[i] = NotEqual(field.SQLName, value)
+		} else {
+			exprs[i] = Equal(field.SQLName, value)
+		}
+	}
+
+	if not {
+		return And(exprs...), nil
+	}
+
+	return Or(exprs...), nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// coerceFilterLiteral converts lit to the Go type t of the model field for column.
+func coerceFilterLiteral(column string, t reflect.Type, lit filterLiteral) (interface{}, error) {
+	t = indirectType(t)
+
+	fail := func(expected string) (interface{}, error) {
+		return nil, &filterLiteralError{lit: lit, msg: fmt.Sprintf("column %q expects %s, got %s", column, expected, lit.describe())}
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		if lit.kind != filterLiteralString && lit.kind != filterLiteralText {
+			return fail("a time")
+		}
+
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			tm, err := time.Parse(layout, lit.text)
+			if err == nil {
+				return tm, nil
+			}
+		}
+
+		return fail("an RFC 3339 time or a date (2006-01-02)")
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if lit.kind != filterLiteralString && lit.kind != filterLiteralText {
+			return fail("a string")
+		}
+
+		return lit.text, nil
+
+	case reflect.Bool:
+		if lit.kind != filterLiteralBool && lit.kind != filterLiteralText {
+			return fail("true or false")
+		}
+
+		b, err := strconv.ParseBool(lit.text)
+		if err != nil {
+			return fail("true or false")
+		}
+
+		return b, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if lit.kind != filterLiteralNumber && lit.kind != filterLiteralText {
+			return fail("an integer")
+		}
+
+		i, err := strconv.ParseInt(lit.text, 10, t.Bits())
+		if isRangeError(err) {
+			return fail(fmt.Sprintf("an integer between %d and %d", int64(-1)<<(t.Bits()-1), int64(1)<<(t.Bits()-1)-1))
+		} else if err != nil {
+			return fail("an integer")
+		}
+
+		return i, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if lit.kind != filterLiteralNumber && lit.kind != filterLiteralText {
+			return fail("an unsigned integer")
+		}
+
+		u, err := strconv.ParseUint(lit.text, 10, t.Bits())
+		if isRangeError(err) {
+			return fail(fmt.Sprintf("an integer between 0 and %d", uint64(math.MaxUint64)>>(64-t.Bits())))
+		} else if err != nil {
+			return fail("an unsigned integer")
+		}
+
+		return u, nil
+
+	case reflect.Float32, reflect.Float64:
+		if lit.kind != filterLiteralNumber && lit.kind != filterLiteralText {
+			return fail("a number")
+		}
+
+		f, err := strconv.ParseFloat(lit.text, t.Bits())
+		if err != nil {
+			return fail("a number")
+		}
+
+		return f, nil
+	}
+
+	// Types such as uuid.UUID are parsed from strings with their UnmarshalText.
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		if lit.kind != filterLiteralString && lit.kind != filterLiteralText {
+			return fail("a string")
+		}
+
+		v := reflect.New(t)
+
+		err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(lit.text))
+		if err != nil {
+			return fail(fmt.Sprintf("a valid %s", t))
+		}
+
+		return v.Elem().Interface(), nil
+	}
+
+	return nil, &filterLiteralError{lit: lit, msg: fmt.Sprintf("column %q of type %s can't be filtered", column, t)}
+}
+
+func (l filterLiteral) describe() string {
+	switch l.kind {
+	case filterLiteralString:
+		return fmt.Sprintf("string '%s'", strings.ReplaceAll(l.text, "'", "''"))
+	case filterLiteralNumber:
+		return fmt.Sprintf("number %s", l.text)
+	case filterLiteralBool:
+		return l.text
+	default:
+		return fmt.Sprintf("%q", l.text)
+	}
+}
+
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,233 @@
+package milo
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type filterEntity struct{}
+
+type filterModel struct {
+	tableName struct{} `pg:"filters"`
+
+	ID uuid.UUID `pg:"id,type:uuid"`
+
+	NameFirst string  `pg:"name_first"`
+	State     *string `pg:"state"`
+	Age       int16   `pg:"age"`
+	Score     float64 `pg:"score"`
+	Visits    uint    `pg:"visits"`
+	Active    bool    `pg:"active,use_zero"`
+
+	Tags []string `pg:"tags,array"`
+
+	Secret string `pg:"secret"`
+
+	CreatedAt time.Time `pg:"created_at"`
+}
+
+var filterEntityModelMap = EntityModelMap{
+	reflect.TypeOf(&filterEntity{}): reflect.TypeOf(&filterModel{}),
+}
+
+func newTestFilterParser(t *testing.T) *FilterParser {
+	p, err := NewFilterParser(filterEntityModelMap, &filterEntity{}, "id", "name_first", "state", "age", "score", "visits", "active", "tags", "created_at")
+	assert.NoError(t, err)
+
+	return p
+}
+
+func TestNewFilterParser(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewFilterParser(filterEntityModelMap, &userEntity{}, "id")
+	assert.Error(err)
+
+	_, err = NewFilterParser(filterEntityModelMap, &filterEntity{})
+	assert.Error(err)
+
+	_, err = NewFilterParser(filterEntityModelMap, &filterEntity{}, "nme_first")
+
+	var unknownErr *UnknownColumnError
+	assert.ErrorAs(err, &unknownErr)
+	assert.Equal([]string{"name_first"}, unknownErr.Suggestions)
+}
+
+func TestFilterParser_Parse(t *testing.T) {
+	id := uuid.MustParse("b2a4f0f6-8c7e-4b37-9f0b-3f1d1c6f3e11")
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected Expression
+	}{
+		{
+			name:     "equal",
+			filter:   "name_first eq 'Jane'",
+			expected: Equal("name_first", "Jane"),
+		},
+		{
+			name:     "escaped quote",
+			filter:   "name_first eq 'O''Brien'",
+			expected: Equal("name_first", "O'Brien"),
+		},
+		{
+			name:     "example",
+			filter:   "name_first eq 'Jane' and (state in ('MA','NY'))",
+			expected: And(Equal("name_first", "Jane"), Or(Equal("state", "MA"), Equal("state", "NY"))),
+		},
+		{
+			name:     "and binds tighter than or",
+			filter:   "age ge 65 or active eq true and score lt 1.5",
+			expected: Or(Gte("age", int64(65)), And(Equal("active", true), Lt("score", 1.5))),
+		},
+		{
+			name:     "parentheses",
+			filter:   "(age gt -1 or visits le 10) AND name_first NE 'Jane'",
+			expected: And(Or(Gt("age", int64(-1)), Lte("visits", uint64(10))), NotEqual("name_first", "Jane")),
+		},
+		{
+			name:     "not in",
+			filter:   "state not in ('MA', 'NY')",
+			expected: And(NotEqual("state", "MA"), NotEqual("state", "NY")),
+		},
+		{
+			name:     "is null",
+			filter:   "state is null or state is not null",
+			expected: Or(IsNull("state"), IsNotNull("state")),
+		},
+		{
+			name:     "has",
+			filter:   "tags has 'intake'",
+			expected: AnyEqual("tags", "intake"),
+		},
+		{
+			name:     "time",
+			filter:   "created_at ge '2021-06-01' and created_at lt '2021-06-01T00:00:00Z'",
+			expected: And(Gte("created_at", day), Lt("created_at", day)),
+		},
+		{
+			name:     "text unmarshaler",
+			filter:   "id eq 'b2a4f0f6-8c7e-4b37-9f0b-3f1d1c6f3e11'",
+			expected: Equal("id", id),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := newTestFilterParser(t).Parse(tt.filter)
+			assert.NoError(err)
+			assert.Equal(tt.expected, actual)
+		})
+	}
+}
+
+func TestFilterParser_ParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{"empty", "", `invalid filter filter at position 1: expected a column or "(", got the end of the filter`},
+		{"unknown column", "secret eq 'a'", `invalid filter filter at position 1: unknown column "secret" (allowed columns are active, age, created_at, id, name_first, score, state, tags, visits)`},
+		{"unknown operator", "age like 1", `invalid filter filter at position 5: unknown operator "like"`},
+		{"missing value", "age eq", `invalid filter filter at position 7: expected a value, got the end of the filter`},
+		{"wrong type", "age eq 'old'", `invalid filter filter at position 8: column "age" expects an integer, got string 'old'`},
+		{"out of range", "age eq 40000", `invalid filter filter at position 8: column "age" expects an integer between -32768 and 32767, got number 40000`},
+		{"negative unsigned", "visits eq -1", `invalid filter filter at position 11: column "visits" expects an unsigned integer, got number -1`},
+		{"bad time", "created_at gt 'yesterday'", `invalid filter filter at position 15: column "created_at" expects an RFC 3339 time or a date (2006-01-02), got string 'yesterday'`},
+		{"has on scalar", "age has 1", `invalid filter filter at position 9: has requires an array column, "age" is int16`},
+		{"in list element", "state in ('MA', 1)", `invalid filter filter at position 17: column "state" expects a string, got number 1`},
+		{"unclosed group", "(age eq 1", `invalid filter filter at position 10: expected ")" to close "(" at position 1, got the end of the filter`},
+		{"trailing", "age eq 1 age eq 2", `invalid filter filter at position 10: expected and, or or the end of the filter, got "age"`},
+		{"unterminated string", "name_first eq 'Jane", `invalid filter filter at position 15: unterminated string`},
+		{"unexpected character", "age = 1", `invalid filter filter at position 5: unexpected character '='`},
+		{"is", "state is 'a'", `invalid filter filter at position 10: expected null or not null, got 'a'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := newTestFilterParser(t).Parse(tt.filter)
+			assert.EqualError(err, tt.expected)
+
+			var filterErr *FilterError
+			assert.ErrorAs(err, &filterErr)
+		})
+	}
+}
+
+func TestFilterParser_ParseQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	values, err := url.ParseQuery("filter=name_first+eq+'Jane'&age[gte]=1&age[ge]=21&state[in]=MA,NY&tags[has]=intake&created_at[null]=false&active[eq]=true&page=2")
+	assert.NoError(err)
+
+	_, err = newTestFilterParser(t).ParseQuery(values)
+
+	var filterErr *FilterError
+	assert.ErrorAs(err, &filterErr)
+	assert.Equal("age[gte]", filterErr.Param)
+
+	values.Del("age[gte]")
+
+	exprs, err := newTestFilterParser(t).ParseQuery(values)
+	assert.NoError(err)
+	assert.Equal([]Expression{
+		Equal("name_first", "Jane"),
+		Equal("active", true),
+		Gte("age", int64(21)),
+		IsNotNull("created_at"),
+		Or(Equal("state", "MA"), Equal("state", "NY")),
+		AnyEqual("tags", "intake"),
+	}, exprs)
+}
+
+func TestFilterParser_ParseQueryOtherParams(t *testing.T) {
+	assert := assert.New(t)
+
+	// Bracketed parameters of columns that can't be filtered on, e.g., for pagination and sorting, aren't filters.
+	values, err := url.ParseQuery("page[size]=10&page[number]=2&sort[by]=name_first&secret[eq]=a&state[eq]=MA&age[ge]=21")
+	assert.NoError(err)
+
+	exprs, err := newTestFilterParser(t).ParseQuery(values)
+	assert.NoError(err)
+	assert.Equal([]Expression{
+		Gte("age", int64(21)),
+		Equal("state", "MA"),
+	}, exprs)
+}
+
+func TestFilterParser_ParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"unknown op", "age[gte]=1", `invalid filter age[gte] at position 1: unknown operator "gte"`},
+		{"wrong type", "age[eq]=old", `invalid filter age[eq] at position 1: column "age" expects an integer, got "old"`},
+		{"null", "state[null]=maybe", `invalid filter state[null] at position 1: expected true or false, got "maybe"`},
+		{"filter", "filter=age+eq", `invalid filter filter at position 7: expected a value, got the end of the filter`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			values, err := url.ParseQuery(tt.query)
+			assert.NoError(err)
+
+			_, err = newTestFilterParser(t).ParseQuery(values)
+			assert.EqualError(err, tt.expected)
+		})
+	}
+}
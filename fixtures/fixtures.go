@@ -0,0 +1,373 @@
+// Package fixtures loads entities from YAML or JSON documents and saves them with a milo.Storer, e.g., to seed
+// integration tests and development databases.
+//
+// A document maps entity type names (the name of the entity struct, e.g., Customer for *domain.Customer) to named
+// fixtures. Fixtures are decoded into their entity type like encoding/json decodes JSON: field names match
+// case-insensitively and json tags are respected. Unknown fields are an error.
+//
+//	Customer:
+//	  jane:
+//	    ID: 4f7c0b8e-8e5e-4b8a-9f0e-1d6f1a2b3c4d
+//	    NameFirst: Jane
+//	Order:
+//	  janes_order:
+//	    ID: 0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f
+//	    CustomerID: $jane.ID
+//
+// A string of the form $name or $name.Field.Field is a reference to another fixture: $name is replaced with the
+// fixture's entity and $name.Field with the value of the field. Fixtures are saved after the fixtures they reference,
+// so the field values are those of the saved entity. Write $$ for a string that starts with a literal $.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Loader loads fixtures of the entity types of an EntityModelMap.
+type Loader struct {
+	entityTypes map[string]reflect.Type
+}
+
+func NewLoader(entityModelMap milo.EntityModelMap) (*Loader, error) {
+	entityTypes := make(map[string]reflect.Type)
+
+	for entityType := range entityModelMap {
+		if entityType.Kind() != reflect.Ptr || entityType.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("entity type %s must be a pointer to a struct", entityType.String())
+		}
+
+		name := entityType.Elem().Name()
+
+		if other, ok := entityTypes[name]; ok {
+			return nil, fmt.Errorf("entity types %s and %s have the same name", other.String(), entityType.String())
+		}
+
+		entityTypes[name] = entityType
+	}
+
+	return &Loader{
+		entityTypes: entityTypes,
+	}, nil
+}
+
+// Fixtures maps fixture names to the saved entities.
+type Fixtures map[string]interface{}
+
+// fixture is a fixture before it is decoded.
+type fixture struct {
+	name       string
+	entityType reflect.Type
+	value      interface{}
+	refs       []string
+	source     string
+}
+
+// LoadFiles loads the fixtures of the YAML or JSON files at paths. Fixtures can reference fixtures in other files.
+func (l *Loader) LoadFiles(ctx context.Context, store milo.Storer, paths ...string) (Fixtures, error) {
+	var fixtures []*fixture
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading fixtures")
+		}
+
+		parsed, err := l.parse(path, data)
+		if err != nil {
+			return nil, err
+		}
+
+		fixtures = append(fixtures, parsed...)
+	}
+
+	return l.save(ctx, store, fixtures)
+}
+
+// Load loads the fixtures of YAML or JSON documents. Fixtures can reference fixtures in other documents.
+func (l *Loader) Load(ctx context.Context, store milo.Storer, docs ...[]byte) (Fixtures, error) {
+	var fixtures []*fixture
+
+	for i, doc := range docs {
+		parsed, err := l.parse(fmt.Sprintf("document %d", i+1), doc)
+		if err != nil {
+			return nil, err
+		}
+
+		fixtures = append(fixtures, parsed...)
+	}
+
+	return l.save(ctx, store, fixtures)
+}
+
+// parse returns the fixtures of a document in the order they appear. JSON is parsed as YAML.
+func (l *Loader) parse(source string, data []byte) ([]*fixture, error) {
+	var doc yaml.Node
+
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", source)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of entity type names to fixtures", source)
+	}
+
+	var fixtures []*fixture
+
+	for i := 0; i < len(root.Content); i += 2 {
+		typeName := root.Content[i].Value
+		byName := root.Content[i+1]
+
+		entityType, ok := l.entityTypes[typeName]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown entity type %q", source, typeName)
+		}
+
+		if byName.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s: expected a mapping of fixture names to %s fixtures", source, typeName)
+		}
+
+		for j := 0; j < len(byName.Content); j += 2 {
+			name := byName.Content[j].Value
+
+			var value interface{}
+
+			err := byName.Content[j+1].Decode(&value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: decoding fixture %q", source, name)
+			}
+
+			f := &fixture{
+				name:       name,
+				entityType: entityType,
+				value:      value,
+				source:     source,
+			}
+
+			collectRefs(value, &f.refs)
+
+			fixtures = append(fixtures, f)
+		}
+	}
+
+	return fixtures, nil
+}
+
+var refRegexp = regexp.MustCompile(`^\$([A-Za-z_][\w-]*)((?:\.[A-Za-z_]\w*)*)$`)
+
+// collectRefs appends the names of the fixtures referenced in value to refs.
+func collectRefs(value interface{}, refs *[]string) {
+	switch v := value.(type) {
+	case string:
+		if m := refRegexp.FindStringSubmatch(v); m != nil {
+			*refs = append(*refs, m[1])
+		}
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		// Sorted, so fixtures are saved in the same order every time.
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			collectRefs(v[key], refs)
+		}
+
+	case []interface{}:
+		for _, elem := range v {
+			collectRefs(elem, refs)
+		}
+	}
+}
+
+// save decodes and saves fixtures, each after the fixtures it references.
+func (l *Loader) save(ctx context.Context, store milo.Storer, fixtures []*fixture) (Fixtures, error) {
+	byName := make(map[string]*fixture)
+
+	for _, f := range fixtures {
+		if other, ok := byName[f.name]; ok {
+			return nil, fmt.Errorf("fixture %q is defined in %s and %s", f.name, other.source, f.source)
+		}
+
+		byName[f.name] = f
+	}
+
+	saved := make(Fixtures)
+	visiting := make(map[string]bool)
+
+	var visit func(f *fixture, path []string) error
+	visit = func(f *fixture, path []string) error {
+		if _, ok := saved[f.name]; ok {
+			return nil
+		}
+
+		path = append(path, f.name)
+
+		if visiting[f.name] {
+			return fmt.Errorf("fixtures reference each other: %s", strings.Join(path, " -> "))
+		}
+
+		visiting[f.name] = true
+
+		for _, ref := range f.refs {
+			dep, ok := byName[ref]
+			if !ok {
+				return fmt.Errorf("fixture %q (%s) references unknown fixture %q", f.name, f.source, ref)
+			}
+
+			err := visit(dep, path)
+			if err != nil {
+				return err
+			}
+		}
+
+		entity, err := decode(f, saved)
+		if err != nil {
+			return err
+		}
+
+		err = store.Save(ctx, entity)
+		if err != nil {
+			return errors.Wrapf(err, "saving fixture %q (%s)", f.name, f.source)
+		}
+
+		saved[f.name] = entity
+
+		return nil
+	}
+
+	for _, f := range fixtures {
+		err := visit(f, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return saved, nil
+}
+
+// decode decodes f into a new entity after replacing its references with values of the saved fixtures.
+func decode(f *fixture, saved Fixtures) (interface{}, error) {
+	value, err := resolveRefs(f.value, saved)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fixture %q (%s)", f.name, f.source)
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding fixture %q (%s)", f.name, f.source)
+	}
+
+	entity := reflect.New(f.entityType.Elem()).Interface()
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(entity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding fixture %q (%s) into %s", f.name, f.source, f.entityType.String())
+	}
+
+	return entity, nil
+}
+
+// resolveRefs returns a copy of value with references replaced by the values they reference.
+func resolveRefs(value interface{}, saved Fixtures) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+
+		m := refRegexp.FindStringSubmatch(v)
+		if m == nil {
+			return v, nil
+		}
+
+		entity := reflect.ValueOf(saved[m[1]])
+
+		var fields []string
+		if len(m[2]) > 0 {
+			fields = strings.Split(m[2][1:], ".")
+		}
+
+		return resolveFields(entity, fields, v)
+
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+
+		for key, elem := range v {
+			r, err := resolveRefs(elem, saved)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[key] = r
+		}
+
+		return resolved, nil
+
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+
+		for i, elem := range v {
+			r, err := resolveRefs(elem, saved)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i] = r
+		}
+
+		return resolved, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// resolveFields returns the value of the fields of v, one inside the other.
+func resolveFields(v reflect.Value, fields []string, ref string) (interface{}, error) {
+	for _, name := range fields {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, nil
+			}
+
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("reference %s: %s isn't a struct", ref, v.Type().String())
+		}
+
+		field, ok := v.Type().FieldByName(name)
+		if !ok || field.PkgPath != "" {
+			return nil, fmt.Errorf("reference %s: %s has no exported field %s", ref, v.Type().String(), name)
+		}
+
+		v = v.FieldByIndex(field.Index)
+	}
+
+	return v.Interface(), nil
+}
@@ -0,0 +1,277 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/eleanorhealth/milo/memstore"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type Customer struct {
+	ID        uuid.UUID
+	NameFirst string
+	Tags      []string
+	Note      string `json:"note_text"`
+	CreatedAt time.Time
+
+	Addresses []*Address
+}
+
+type Address struct {
+	ID   string
+	City string
+}
+
+type Order struct {
+	ID         string
+	CustomerID uuid.UUID
+	City       string
+
+	Customer *Customer
+}
+
+type customerModel struct {
+	tableName struct{} `pg:"customers"`
+
+	ID        uuid.UUID `pg:"id,type:uuid"`
+	NameFirst string    `pg:"name_first"`
+
+	entity Customer
+}
+
+func (c *customerModel) FromEntity(e interface{}) error {
+	c.ID = e.(*Customer).ID
+	c.NameFirst = e.(*Customer).NameFirst
+	c.entity = *e.(*Customer)
+
+	return nil
+}
+
+func (c *customerModel) ToEntity() (interface{}, error) {
+	entity := c.entity
+
+	return &entity, nil
+}
+
+func (c *customerModel) BeforeSave(ctx context.Context, store milo.Storer, entity interface{}) error {
+	// The hook sees the decoded entity.
+	entity.(*Customer).Note = "saved " + entity.(*Customer).Note
+
+	return nil
+}
+
+func (c *customerModel) BeforeDelete(ctx context.Context, store milo.Storer, entity interface{}) error {
+	return nil
+}
+
+type orderModel struct {
+	tableName struct{} `pg:"orders"`
+
+	ID string `pg:"id"`
+
+	entity Order
+}
+
+func (o *orderModel) FromEntity(e interface{}) error {
+	o.ID = e.(*Order).ID
+	o.entity = *e.(*Order)
+
+	return nil
+}
+
+func (o *orderModel) ToEntity() (interface{}, error) {
+	entity := o.entity
+
+	return &entity, nil
+}
+
+var entityModelMap = milo.EntityModelMap{
+	reflect.TypeOf(&Customer{}): reflect.TypeOf(&customerModel{}),
+	reflect.TypeOf(&Order{}):    reflect.TypeOf(&orderModel{}),
+}
+
+func newTestLoader(t *testing.T) (*Loader, *memstore.Store) {
+	loader, err := NewLoader(entityModelMap)
+	assert.NoError(t, err)
+
+	store, err := memstore.NewStore(entityModelMap)
+	assert.NoError(t, err)
+
+	return loader, store
+}
+
+func TestLoader_Load(t *testing.T) {
+	assert := assert.New(t)
+
+	loader, store := newTestLoader(t)
+
+	// The order is saved after the customer it references, even though it comes first.
+	orders := []byte(`
+Order:
+  janes_order:
+    ID: order-1
+    CustomerID: $jane.ID
+    City: $jane.Addresses.City
+    Customer: $jane
+  price:
+    ID: $$5
+`)
+
+	customers := []byte(`{
+  "Customer": {
+    "jane": {
+      "ID": "4f7c0b8e-8e5e-4b8a-9f0e-1d6f1a2b3c4d",
+      "namefirst": "Jane",
+      "Tags": ["vip"],
+      "note_text": "hi",
+      "CreatedAt": "2021-03-14T15:09:26Z",
+      "Addresses": [{"ID": "a1", "City": "Boston"}]
+    }
+  }
+}`)
+
+	_, err := loader.Load(context.Background(), store, orders, customers)
+	assert.EqualError(err, "fixture \"janes_order\" (document 1): reference $jane.Addresses.City: []*fixtures.Address isn't a struct")
+
+	orders = []byte(`
+Order:
+  janes_order:
+    ID: order-1
+    CustomerID: $jane.ID
+    Customer: $jane
+  price:
+    ID: $$5
+`)
+
+	loader, store = newTestLoader(t)
+
+	fixtures, err := loader.Load(context.Background(), store, orders, customers)
+	assert.NoError(err)
+	assert.Len(fixtures, 3)
+
+	jane := fixtures["jane"].(*Customer)
+	assert.Equal("Jane", jane.NameFirst)
+	assert.Equal("saved hi", jane.Note)
+	assert.Equal(time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC), jane.CreatedAt)
+	assert.Equal("Boston", jane.Addresses[0].City)
+
+	order := &Order{}
+	err = store.FindByID(context.Background(), order, "order-1")
+	assert.NoError(err)
+	assert.Equal(jane.ID, order.CustomerID)
+	assert.Equal("Jane", order.Customer.NameFirst)
+	assert.Equal("saved hi", order.Customer.Note, "references see the saved entity")
+
+	err = store.FindByID(context.Background(), &Order{}, "$5")
+	assert.NoError(err)
+
+	err = store.FindByID(context.Background(), &Customer{}, jane.ID)
+	assert.NoError(err)
+}
+
+func TestLoader_LoadFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	loader, store := newTestLoader(t)
+
+	dir := t.TempDir()
+
+	customers := filepath.Join(dir, "customers.yml")
+	err := os.WriteFile(customers, []byte("Customer:\n  jane:\n    ID: 4f7c0b8e-8e5e-4b8a-9f0e-1d6f1a2b3c4d\n"), 0644)
+	assert.NoError(err)
+
+	orders := filepath.Join(dir, "orders.json")
+	err = os.WriteFile(orders, []byte(`{"Order": {"order": {"ID": "order-1", "CustomerID": "$jane.ID"}}}`), 0644)
+	assert.NoError(err)
+
+	fixtures, err := loader.LoadFiles(context.Background(), store, orders, customers)
+	assert.NoError(err)
+	assert.Len(fixtures, 2)
+
+	_, err = loader.LoadFiles(context.Background(), store, filepath.Join(dir, "missing.yml"))
+	assert.Error(err)
+}
+
+func TestLoader_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		docs        []string
+		expectedErr string
+	}{
+		{
+			name:        "unknown entity type",
+			docs:        []string{"Product:\n  p:\n    ID: 1\n"},
+			expectedErr: `document 1: unknown entity type "Product"`,
+		},
+		{
+			name:        "not a mapping",
+			docs:        []string{"- Customer\n"},
+			expectedErr: "document 1: expected a mapping of entity type names to fixtures",
+		},
+		{
+			name:        "unknown field",
+			docs:        []string{"Order:\n  o:\n    ID: o\n    Total: 5\n"},
+			expectedErr: `decoding fixture "o" (document 1) into *fixtures.Order: json: unknown field "Total"`,
+		},
+		{
+			name:        "duplicate name",
+			docs:        []string{"Order:\n  o:\n    ID: o\n", "Order:\n  o:\n    ID: p\n"},
+			expectedErr: `fixture "o" is defined in document 1 and document 2`,
+		},
+		{
+			name:        "unknown reference",
+			docs:        []string{"Order:\n  o:\n    ID: $p.ID\n"},
+			expectedErr: `fixture "o" (document 1) references unknown fixture "p"`,
+		},
+		{
+			name:        "cycle",
+			docs:        []string{"Order:\n  o:\n    ID: $p.ID\n  p:\n    ID: $o.ID\n"},
+			expectedErr: "fixtures reference each other: o -> p -> o",
+		},
+		{
+			name:        "unknown reference field",
+			docs:        []string{"Order:\n  o:\n    ID: o\n  p:\n    ID: $o.Name\n"},
+			expectedErr: `fixture "p" (document 1): reference $o.Name: fixtures.Order has no exported field Name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader, store := newTestLoader(t)
+
+			docs := make([][]byte, len(tt.docs))
+			for i, doc := range tt.docs {
+				docs[i] = []byte(doc)
+			}
+
+			_, err := loader.Load(context.Background(), store, docs...)
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+// Store has the same name as memstore.Store.
+type Store struct{}
+
+func TestNewLoader(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewLoader(milo.EntityModelMap{
+		reflect.TypeOf(Customer{}): reflect.TypeOf(&customerModel{}),
+	})
+	assert.EqualError(err, "entity type fixtures.Customer must be a pointer to a struct")
+
+	_, err = NewLoader(milo.EntityModelMap{
+		reflect.TypeOf(&Store{}):          reflect.TypeOf(&customerModel{}),
+		reflect.TypeOf(&memstore.Store{}): reflect.TypeOf(&customerModel{}),
+	})
+	assert.Error(err)
+	assert.Contains(err.Error(), "have the same name")
+}
@@ -0,0 +1,228 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// database holds the committed rows of every model type and the row locks of open transactions.
+type database struct {
+	mu sync.Mutex
+	// released is signaled when row locks are released.
+	released *sync.Cond
+
+	tables map[reflect.Type]*table
+	locks  map[rowID]*transaction
+}
+
+func newDatabase() *database {
+	db := &database{
+		tables: make(map[reflect.Type]*table),
+		locks:  make(map[rowID]*transaction),
+	}
+
+	db.released = sync.NewCond(&db.mu)
+
+	return db
+}
+
+// table holds the rows of a model type in insertion order. Rows are model pointers that are never shared with callers.
+type table struct {
+	keys []string
+	rows map[string]interface{}
+}
+
+// rowID identifies a row by its model type and primary key.
+type rowID struct {
+	modelType reflect.Type
+	key       string
+}
+
+// transaction holds the writes of a transaction until it is committed and the rows it has locked.
+type transaction struct {
+	// writes maps rows to their new model, or nil if they were deleted.
+	writes map[rowID]interface{}
+	// order is the order rows were first written in, so new rows are found in insertion order.
+	order  []rowID
+	locked []rowID
+}
+
+func newTransaction() *transaction {
+	return &transaction{
+		writes: make(map[rowID]interface{}),
+	}
+}
+
+// primaryKey returns the row ID of model, a model pointer.
+func primaryKey(model interface{}) (rowID, error) {
+	modelType := reflect.TypeOf(model)
+	table := orm.GetTable(modelType.Elem())
+
+	if len(table.PKs) == 0 {
+		return rowID{}, fmt.Errorf("model type %s must have a primary key", modelType.String())
+	}
+
+	strct := reflect.ValueOf(model).Elem()
+
+	values := make([]string, len(table.PKs))
+	for i, pk := range table.PKs {
+		values[i] = fmt.Sprint(strct.FieldByIndex(pk.Index).Interface())
+	}
+
+	return rowID{
+		modelType: modelType,
+		key:       strings.Join(values, ","),
+	}, nil
+}
+
+// rows returns the rows of modelType that tx sees: the committed rows with the writes of tx applied. db.mu must be
+// held.
+func (db *database) rows(modelType reflect.Type, tx *transaction) []interface{} {
+	var rows []interface{}
+
+	t := db.tables[modelType]
+	if t != nil {
+		for _, key := range t.keys {
+			row := t.rows[key]
+
+			if tx != nil {
+				if model, ok := tx.writes[rowID{modelType: modelType, key: key}]; ok {
+					row = model
+				}
+			}
+
+			if row != nil {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	if tx == nil {
+		return rows
+	}
+
+	for _, id := range tx.order {
+		if id.modelType != modelType {
+			continue
+		}
+
+		if t != nil {
+			if _, ok := t.rows[id.key]; ok {
+				continue
+			}
+		}
+
+		if model := tx.writes[id]; model != nil {
+			rows = append(rows, model)
+		}
+	}
+
+	return rows
+}
+
+// write records model (nil to delete the row) as the new version of the row in tx. db.mu must be held.
+func (db *database) write(tx *transaction, id rowID, model interface{}) {
+	if _, ok := tx.writes[id]; !ok {
+		tx.order = append(tx.order, id)
+	}
+
+	tx.writes[id] = model
+}
+
+// commit applies the writes of tx and releases its locks. db.mu must be held.
+func (db *database) commit(tx *transaction) {
+	for _, id := range tx.order {
+		t := db.tables[id.modelType]
+		if t == nil {
+			t = &table{
+				rows: make(map[string]interface{}),
+			}
+
+			db.tables[id.modelType] = t
+		}
+
+		model := tx.writes[id]
+		_, exists := t.rows[id.key]
+
+		switch {
+		case model == nil && exists:
+			delete(t.rows, id.key)
+
+			for i, key := range t.keys {
+				if key == id.key {
+					t.keys = append(t.keys[:i:i], t.keys[i+1:]...)
+					break
+				}
+			}
+
+		case model != nil && !exists:
+			t.keys = append(t.keys, id.key)
+			fallthrough
+
+		case model != nil:
+			t.rows[id.key] = model
+		}
+	}
+
+	db.release(tx)
+}
+
+// release releases the locks of tx. db.mu must be held.
+func (db *database) release(tx *transaction) {
+	for _, id := range tx.locked {
+		delete(db.locks, id)
+	}
+
+	tx.locked = nil
+
+	db.released.Broadcast()
+}
+
+// lockedByOther returns whether the row is locked by a transaction other than tx. db.mu must be held.
+func (db *database) lockedByOther(tx *transaction, id rowID) bool {
+	owner, ok := db.locks[id]
+
+	return ok && owner != tx
+}
+
+// lock locks the row for tx until it ends, like SELECT ... FOR UPDATE. The row must not be locked by another
+// transaction. Outside a transaction (tx is nil), the lock would be released right away, so nothing is locked.
+// db.mu must be held.
+func (db *database) lock(tx *transaction, id rowID) {
+	if tx == nil {
+		return
+	}
+
+	if _, ok := db.locks[id]; ok {
+		return
+	}
+
+	db.locks[id] = tx
+	tx.locked = append(tx.locked, id)
+}
+
+// wait waits until locks are released or ctx is done. db.mu must be held.
+func (db *database) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			db.mu.Lock()
+			db.released.Broadcast()
+			db.mu.Unlock()
+
+		case <-done:
+		}
+	}()
+
+	db.released.Wait()
+
+	return ctx.Err()
+}
@@ -0,0 +1,418 @@
+// Package memstore provides an in-memory milo.Storer for unit tests.
+//
+// Store keeps copies of storage models in memory and works like milo.Store: entities are converted with the models'
+// FromEntity and ToEntity, entities and models are validated, hooks are called, expressions are evaluated with
+// milo.SelectModels and the finders return a *milo.NotFoundError. Store.Transaction runs fn in a transaction that is
+// rolled back if fn returns an error; writes in a transaction are only seen by it until it is committed. The ForUpdate
+// finders lock the rows they find until the end of the transaction: other ForUpdate finders, Save and Delete wait for
+// the lock, and with skipLocked, the finders skip locked rows.
+//
+// Unlike Postgres, relations are stored with the model that owns them, so related models can't be shared between
+// models and there are no constraints other than the primary key.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/pkg/errors"
+)
+
+type Store struct {
+	db             *database
+	tx             *transaction
+	entityModelMap milo.EntityModelMap
+}
+
+var _ milo.Storer = (*Store)(nil)
+
+func NewStore(entityModelMap milo.EntityModelMap) (*Store, error) {
+	for entityType, modelType := range entityModelMap {
+		if entityType.Kind() != reflect.Ptr {
+			return nil, fmt.Errorf("entity type %s must be a pointer", entityType.String())
+		}
+
+		if modelType.Kind() != reflect.Ptr {
+			return nil, fmt.Errorf("model type %s must be a pointer", modelType.String())
+		}
+
+		modelInterfaceType := reflect.TypeOf((*milo.Model)(nil)).Elem()
+
+		if !modelType.Implements(modelInterfaceType) {
+			return nil, fmt.Errorf("model type %s must implement %s", modelType.String(), modelInterfaceType.String())
+		}
+	}
+
+	return &Store{
+		db:             newDatabase(),
+		entityModelMap: entityModelMap,
+	}, nil
+}
+
+// Transaction runs function fn in a transaction. If fn returns an error, the transaction is rolled back. Otherwise, the transaction is committed.
+func (s *Store) Transaction(ctx context.Context, fn func(txStore milo.Storer) error) error {
+	if s.tx != nil {
+		return errors.New("already in a transaction")
+	}
+
+	return s.runInTransaction(func(txStore *Store) error {
+		return fn(txStore)
+	})
+}
+
+// runInTransaction runs fn with a store for a new transaction, like pg.DB.RunInTransaction.
+func (s *Store) runInTransaction(fn func(txStore *Store) error) error {
+	txStore := &Store{
+		db:             s.db,
+		tx:             newTransaction(),
+		entityModelMap: s.entityModelMap,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.db.mu.Lock()
+			s.db.release(txStore.tx)
+			s.db.mu.Unlock()
+
+			panic(r)
+		}
+	}()
+
+	err := fn(txStore)
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if err != nil {
+		s.db.release(txStore.tx)
+		return err
+	}
+
+	s.db.commit(txStore.tx)
+
+	return nil
+}
+
+func (s *Store) FindAll(ctx context.Context, entities interface{}) error {
+	return s.findMany(ctx, entities, false, false, nil)
+}
+
+func (s *Store) FindBy(ctx context.Context, entities interface{}, exprs ...milo.Expression) error {
+	return s.findMany(ctx, entities, false, false, exprs)
+}
+
+func (s *Store) FindByForUpdate(ctx context.Context, entities interface{}, skipLocked bool, exprs ...milo.Expression) error {
+	return s.findMany(ctx, entities, true, skipLocked, exprs)
+}
+
+func (s *Store) FindOneBy(ctx context.Context, entity interface{}, exprs ...milo.Expression) error {
+	return s.findOne(ctx, entity, nil, false, false, exprs)
+}
+
+func (s *Store) FindOneByForUpdate(ctx context.Context, entity interface{}, skipLocked bool, exprs ...milo.Expression) error {
+	return s.findOne(ctx, entity, nil, true, skipLocked, exprs)
+}
+
+func (s *Store) FindByID(ctx context.Context, entity interface{}, id interface{}) error {
+	return s.findByID(ctx, entity, id, false, false)
+}
+
+func (s *Store) FindByIDForUpdate(ctx context.Context, entity interface{}, id interface{}, skipLocked bool) error {
+	return s.findByID(ctx, entity, id, true, skipLocked)
+}
+
+func (s *Store) findMany(ctx context.Context, entities interface{}, forUpdate bool, skipLocked bool, exprs []milo.Expression) error {
+	entitiesType := reflect.TypeOf(entities)
+
+	if entitiesType.Kind() != reflect.Ptr {
+		return errors.New("entities must be a pointer")
+	}
+
+	if entitiesType.Elem().Kind() != reflect.Slice {
+		return errors.New("entities must be a slice")
+	}
+
+	entityType := entitiesType.Elem().Elem()
+
+	if entityType.Kind() != reflect.Ptr {
+		return errors.New("entities must be a slice of pointers")
+	}
+
+	modelType, ok := s.entityModelMap[entityType]
+	if !ok {
+		return fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	models, err := s.selectModels(ctx, modelType, exprs, forUpdate, skipLocked, false)
+	if err != nil {
+		return err
+	}
+
+	entitiesValue := reflect.ValueOf(entities).Elem()
+
+	for _, model := range models {
+		entity, err := model.(milo.Model).ToEntity()
+		if err != nil {
+			return errors.Wrap(err, "converting model to entity")
+		}
+
+		entitiesValue.Set(reflect.Append(entitiesValue, reflect.ValueOf(entity)))
+	}
+
+	return nil
+}
+
+// findOne sets entity to the first entity selected by exprs. id is the ID exprs select by, if any, for the
+// milo.NotFoundError.
+func (s *Store) findOne(ctx context.Context, entity interface{}, id interface{}, forUpdate bool, skipLocked bool, exprs []milo.Expression) error {
+	entityType := reflect.TypeOf(entity)
+
+	modelType, ok := s.entityModelMap[entityType]
+	if !ok {
+		return fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	models, err := s.selectModels(ctx, modelType, exprs, forUpdate, skipLocked, true)
+	if err != nil {
+		return err
+	}
+
+	if len(models) == 0 {
+		lock := milo.LockNone
+		if forUpdate {
+			lock = milo.LockForUpdate
+			if skipLocked {
+				lock = milo.LockForUpdateSkipLocked
+			}
+		}
+
+		if id != nil {
+			exprs = nil
+		}
+
+		return milo.NewNotFoundError(entity, id, exprs, lock)
+	}
+
+	toEntity, err := models[0].(milo.Model).ToEntity()
+	if err != nil {
+		return errors.Wrap(err, "converting model to entity")
+	}
+
+	entityValue := reflect.ValueOf(entity)
+	reflect.Indirect(entityValue).Set(reflect.Indirect(reflect.ValueOf(toEntity)))
+
+	return nil
+}
+
+func (s *Store) findByID(ctx context.Context, entity interface{}, id interface{}, forUpdate bool, skipLocked bool) error {
+	entityType := reflect.TypeOf(entity)
+
+	modelType, ok := s.entityModelMap[entityType]
+	if !ok {
+		return fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	var exprs []milo.Expression
+	for _, pk := range orm.GetTable(modelType.Elem()).PKs {
+		exprs = append(exprs, milo.Equal(pk.SQLName, id))
+	}
+
+	return s.findOne(ctx, entity, id, forUpdate, skipLocked, exprs)
+}
+
+// selectModels returns copies of the models of modelType selected by exprs. If first is true, the models are ordered
+// by primary key after the orders in exprs and only the first one is returned, like go-pg's Query.First. If forUpdate
+// is true, the selected models are locked (see database.lock); models locked by another transaction are skipped if
+// skipLocked is true, otherwise selectModels waits for their lock and selects again.
+func (s *Store) selectModels(ctx context.Context, modelType reflect.Type, exprs []milo.Expression, forUpdate bool, skipLocked bool, first bool) ([]interface{}, error) {
+	if first {
+		exprs = append(exprs[:len(exprs):len(exprs)], orderByPK(modelType)...)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		models, err := milo.SelectModels(s.db.rows(modelType, s.tx), exprs...)
+		if err != nil {
+			return nil, errors.Wrap(err, "applying expressions to models")
+		}
+
+		if !forUpdate {
+			if first && len(models) > 1 {
+				models = models[:1]
+			}
+
+			return copyModels(models), nil
+		}
+
+		var unlocked []interface{}
+		var ids []rowID
+		wait := false
+
+		for _, model := range models {
+			id, err := primaryKey(model)
+			if err != nil {
+				return nil, err
+			}
+
+			if s.db.lockedByOther(s.tx, id) {
+				if skipLocked {
+					continue
+				}
+
+				wait = true
+				break
+			}
+
+			unlocked = append(unlocked, model)
+			ids = append(ids, id)
+
+			if first {
+				break
+			}
+		}
+
+		if wait {
+			err = s.db.wait(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		for _, id := range ids {
+			s.db.lock(s.tx, id)
+		}
+
+		return copyModels(unlocked), nil
+	}
+}
+
+func orderByPK(modelType reflect.Type) []milo.Expression {
+	var orders []milo.Expression
+	for _, pk := range orm.GetTable(modelType.Elem()).PKs {
+		orders = append(orders, milo.OrderBy(pk.SQLName))
+	}
+
+	return orders
+}
+
+func copyModels(models []interface{}) []interface{} {
+	copies := make([]interface{}, len(models))
+	for i, model := range models {
+		copies[i] = copyModel(model)
+	}
+
+	return copies
+}
+
+func (s *Store) Save(ctx context.Context, entity interface{}) error {
+	entityType := reflect.TypeOf(entity)
+
+	modelType, ok := s.entityModelMap[entityType]
+	if !ok {
+		return fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	modelValue := reflect.New(modelType.Elem())
+	model := modelValue.Interface().(milo.Model)
+
+	err := model.FromEntity(entity)
+	if err != nil {
+		return errors.Wrapf(err, "converting entity to model")
+	}
+
+	err = milo.Validate(ctx, entity, model)
+	if err != nil {
+		return err
+	}
+
+	if s.tx == nil {
+		return s.runInTransaction(func(txStore *Store) error {
+			return txStore.save(ctx, entity, model)
+		})
+	}
+
+	return s.save(ctx, entity, model)
+}
+
+func (s *Store) save(ctx context.Context, entity interface{}, model milo.Model) error {
+	if model, ok := model.(milo.Hook); ok {
+		err := model.BeforeSave(ctx, s, entity)
+		if err != nil {
+			return errors.Wrap(err, "calling before save hook")
+		}
+	}
+
+	return s.write(ctx, model, copyModel(model))
+}
+
+func (s *Store) Delete(ctx context.Context, entity interface{}) error {
+	entityType := reflect.TypeOf(entity)
+
+	modelType, ok := s.entityModelMap[entityType]
+	if !ok {
+		return fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	modelValue := reflect.New(modelType.Elem())
+	model := modelValue.Interface().(milo.Model)
+
+	err := model.FromEntity(entity)
+	if err != nil {
+		return errors.Wrapf(err, "converting entity to model")
+	}
+
+	if s.tx == nil {
+		return s.runInTransaction(func(txStore *Store) error {
+			return txStore.delete(ctx, entity, model)
+		})
+	}
+
+	return s.delete(ctx, entity, model)
+}
+
+func (s *Store) delete(ctx context.Context, entity interface{}, model milo.Model) error {
+	if model, ok := model.(milo.Hook); ok {
+		err := model.BeforeDelete(ctx, s, entity)
+		if err != nil {
+			return errors.Wrap(err, "calling before delete hook")
+		}
+	}
+
+	return s.write(ctx, model, nil)
+}
+
+// write locks the row of model, waiting for other transactions like UPDATE and DELETE do, and records row (nil to
+// delete it) as its new version in the store's transaction.
+func (s *Store) write(ctx context.Context, model milo.Model, row interface{}) error {
+	id, err := primaryKey(model)
+	if err != nil {
+		return err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for s.db.lockedByOther(s.tx, id) {
+		err = s.db.wait(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.db.lock(s.tx, id)
+	s.db.write(s.tx, id, row)
+
+	return nil
+}
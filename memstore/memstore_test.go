@@ -0,0 +1,427 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/stretchr/testify/assert"
+)
+
+type customerEntity struct {
+	ID   string
+	Name string
+	Age  int
+	Tags []string
+
+	Addresses []*addressEntity
+
+	beforeSaveFunc   func(ctx context.Context, store milo.Storer, entity interface{}) error
+	beforeDeleteFunc func(ctx context.Context, store milo.Storer, entity interface{}) error
+	validateFunc     func(ctx context.Context) error
+}
+
+func (c *customerEntity) Validate(ctx context.Context) error {
+	if c.validateFunc == nil {
+		return nil
+	}
+
+	return c.validateFunc(ctx)
+}
+
+type addressEntity struct {
+	ID   string
+	City string
+}
+
+type customerModel struct {
+	tableName struct{} `pg:"customers"`
+
+	ID   string   `pg:"id"`
+	Name string   `pg:"name"`
+	Age  int      `pg:"age"`
+	Tags []string `pg:"tags,array"`
+
+	Addresses []*addressModel `pg:"rel:has-many,join_fk:customer_id"`
+
+	beforeSaveFunc   func(ctx context.Context, store milo.Storer, entity interface{}) error
+	beforeDeleteFunc func(ctx context.Context, store milo.Storer, entity interface{}) error
+}
+
+type addressModel struct {
+	tableName struct{} `pg:"addresses"`
+
+	ID         string `pg:"id"`
+	CustomerID string `pg:"customer_id"`
+	City       string `pg:"city"`
+}
+
+var _ milo.Model = (*customerModel)(nil)
+var _ milo.Hook = (*customerModel)(nil)
+
+func (c *customerModel) FromEntity(e interface{}) error {
+	entity := e.(*customerEntity)
+
+	c.ID = entity.ID
+	c.Name = entity.Name
+	c.Age = entity.Age
+	c.Tags = entity.Tags
+
+	for _, address := range entity.Addresses {
+		c.Addresses = append(c.Addresses, &addressModel{
+			ID:         address.ID,
+			CustomerID: entity.ID,
+			City:       address.City,
+		})
+	}
+
+	c.beforeSaveFunc = entity.beforeSaveFunc
+	c.beforeDeleteFunc = entity.beforeDeleteFunc
+
+	return nil
+}
+
+func (c *customerModel) ToEntity() (interface{}, error) {
+	entity := &customerEntity{
+		ID:   c.ID,
+		Name: c.Name,
+		Age:  c.Age,
+		Tags: c.Tags,
+	}
+
+	for _, address := range c.Addresses {
+		entity.Addresses = append(entity.Addresses, &addressEntity{
+			ID:   address.ID,
+			City: address.City,
+		})
+	}
+
+	return entity, nil
+}
+
+func (c *customerModel) BeforeSave(ctx context.Context, store milo.Storer, entity interface{}) error {
+	if c.beforeSaveFunc == nil {
+		return nil
+	}
+
+	return c.beforeSaveFunc(ctx, store, entity)
+}
+
+func (c *customerModel) BeforeDelete(ctx context.Context, store milo.Storer, entity interface{}) error {
+	if c.beforeDeleteFunc == nil {
+		return nil
+	}
+
+	return c.beforeDeleteFunc(ctx, store, entity)
+}
+
+func newTestStore(t *testing.T) *Store {
+	store, err := NewStore(milo.EntityModelMap{
+		reflect.TypeOf(&customerEntity{}): reflect.TypeOf(&customerModel{}),
+	})
+	assert.NoError(t, err)
+
+	return store
+}
+
+func TestNewStore(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewStore(milo.EntityModelMap{
+		reflect.TypeOf(customerEntity{}): reflect.TypeOf(&customerModel{}),
+	})
+	assert.EqualError(err, "entity type memstore.customerEntity must be a pointer")
+
+	_, err = NewStore(milo.EntityModelMap{
+		reflect.TypeOf(&customerEntity{}): reflect.TypeOf(&addressModel{}),
+	})
+	assert.EqualError(err, "model type *memstore.addressModel must implement milo.Model")
+}
+
+func TestStore(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	jane := &customerEntity{
+		ID:   "2",
+		Name: "Jane",
+		Age:  34,
+		Tags: []string{"vip"},
+		Addresses: []*addressEntity{
+			{ID: "a", City: "Boston"},
+		},
+	}
+	john := &customerEntity{
+		ID:   "1",
+		Name: "John",
+		Age:  51,
+	}
+
+	assert.NoError(store.Save(ctx, jane))
+	assert.NoError(store.Save(ctx, john))
+
+	// Changing a saved entity doesn't change the store.
+	jane.Tags[0] = "changed"
+	jane.Addresses[0].City = "changed"
+
+	found := &customerEntity{}
+	err := store.FindByID(ctx, found, "2")
+	assert.NoError(err)
+	assert.Equal("Jane", found.Name)
+	assert.Equal([]string{"vip"}, found.Tags)
+	assert.Equal("Boston", found.Addresses[0].City)
+
+	err = store.FindByID(ctx, &customerEntity{}, "3")
+	assert.ErrorIs(err, milo.ErrNotFound)
+
+	var notFoundErr *milo.NotFoundError
+	if assert.ErrorAs(err, &notFoundErr) {
+		assert.Equal(reflect.TypeOf(&customerEntity{}), notFoundErr.EntityType)
+		assert.Equal("3", notFoundErr.ID)
+		assert.Nil(notFoundErr.Expressions)
+		assert.Equal(milo.LockNone, notFoundErr.Lock)
+	}
+
+	// FindAll returns entities in the order they were saved.
+	var all []*customerEntity
+	err = store.FindAll(ctx, &all)
+	assert.NoError(err)
+	assert.Len(all, 2)
+	assert.Equal("2", all[0].ID)
+	assert.Equal("1", all[1].ID)
+
+	var older []*customerEntity
+	err = store.FindBy(ctx, &older, milo.Gt("age", 40))
+	assert.NoError(err)
+	assert.Len(older, 1)
+	assert.Equal("John", older[0].Name)
+
+	var ordered []*customerEntity
+	err = store.FindBy(ctx, &ordered, milo.IsNotNull("name"), milo.OrderByDesc("age"))
+	assert.NoError(err)
+	assert.Len(ordered, 2)
+	assert.Equal("John", ordered[0].Name)
+	assert.Equal("Jane", ordered[1].Name)
+
+	// FindOneBy returns the entity with the lowest primary key, like go-pg's First.
+	one := &customerEntity{}
+	err = store.FindOneBy(ctx, one)
+	assert.NoError(err)
+	assert.Equal("1", one.ID)
+
+	err = store.FindOneBy(ctx, one, milo.ArrayContains("tags", []string{"vip"}))
+	assert.NoError(err)
+	assert.Equal("2", one.ID)
+
+	err = store.FindOneBy(ctx, one, milo.Equal("nam", "Jane"))
+	assert.Error(err)
+	assert.Contains(err.Error(), `unknown column "nam"`)
+
+	john.Name = "Johnny"
+	assert.NoError(store.Save(ctx, john))
+
+	err = store.FindByID(ctx, found, "1")
+	assert.NoError(err)
+	assert.Equal("Johnny", found.Name)
+
+	assert.NoError(store.Delete(ctx, john))
+
+	err = store.FindByID(ctx, found, "1")
+	assert.ErrorIs(err, milo.ErrNotFound)
+
+	err = store.FindByID(ctx, &addressEntity{}, "a")
+	assert.EqualError(err, "unable to find model type for entity type *memstore.addressEntity")
+}
+
+func TestStore_Transaction(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	jane := &customerEntity{ID: "1", Name: "Jane"}
+	assert.NoError(store.Save(ctx, jane))
+
+	errRollback := errors.New("rollback")
+
+	err := store.Transaction(ctx, func(txStore milo.Storer) error {
+		assert.NoError(txStore.Save(ctx, &customerEntity{ID: "2", Name: "John"}))
+		assert.NoError(txStore.Delete(ctx, jane))
+
+		// The transaction sees its own writes, other stores don't.
+		var inTx []*customerEntity
+		assert.NoError(txStore.FindAll(ctx, &inTx))
+		assert.Len(inTx, 1)
+		assert.Equal("John", inTx[0].Name)
+
+		var outside []*customerEntity
+		assert.NoError(store.FindAll(ctx, &outside))
+		assert.Len(outside, 1)
+		assert.Equal("Jane", outside[0].Name)
+
+		err := txStore.Transaction(ctx, func(milo.Storer) error {
+			return nil
+		})
+		assert.EqualError(err, "already in a transaction")
+
+		return errRollback
+	})
+	assert.ErrorIs(err, errRollback)
+
+	var all []*customerEntity
+	assert.NoError(store.FindAll(ctx, &all))
+	assert.Len(all, 1)
+	assert.Equal("Jane", all[0].Name)
+
+	err = store.Transaction(ctx, func(txStore milo.Storer) error {
+		return txStore.Save(ctx, &customerEntity{ID: "2", Name: "John"})
+	})
+	assert.NoError(err)
+
+	all = nil
+	assert.NoError(store.FindAll(ctx, &all))
+	assert.Len(all, 2)
+}
+
+func TestStore_Hooks(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	// Writes in a hook are rolled back with the save.
+	entity := &customerEntity{
+		ID: "1",
+		beforeSaveFunc: func(ctx context.Context, txStore milo.Storer, entity interface{}) error {
+			assert.NotSame(store, txStore)
+
+			err := txStore.Save(ctx, &customerEntity{ID: "2"})
+			assert.NoError(err)
+
+			return errors.New("test")
+		},
+	}
+
+	err := store.Save(ctx, entity)
+	assert.EqualError(err, "calling before save hook: test")
+
+	var all []*customerEntity
+	assert.NoError(store.FindAll(ctx, &all))
+	assert.Empty(all)
+
+	called := false
+	entity = &customerEntity{
+		ID: "1",
+		beforeDeleteFunc: func(ctx context.Context, txStore milo.Storer, entity interface{}) error {
+			called = true
+
+			return errors.New("test")
+		},
+	}
+
+	assert.NoError(store.Save(ctx, entity))
+
+	err = store.Delete(ctx, entity)
+	assert.EqualError(err, "calling before delete hook: test")
+	assert.True(called)
+
+	assert.NoError(store.FindByID(ctx, &customerEntity{}, "1"))
+}
+
+func TestStore_Validate(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	called := false
+	entity := &customerEntity{
+		ID: "1",
+		validateFunc: func(ctx context.Context) error {
+			var errs milo.ValidationErrors
+			errs.Add("name", "is required")
+
+			return errs.Err()
+		},
+		beforeSaveFunc: func(ctx context.Context, txStore milo.Storer, entity interface{}) error {
+			called = true
+
+			return nil
+		},
+	}
+
+	err := store.Save(ctx, entity)
+	assert.ErrorIs(err, milo.ErrValidation)
+	assert.EqualError(err, "validating entity: validation failed: name: is required")
+	assert.False(called)
+
+	err = store.FindByID(ctx, &customerEntity{}, "1")
+	assert.ErrorIs(err, milo.ErrNotFound)
+}
+
+func TestStore_ForUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	assert.NoError(store.Save(ctx, &customerEntity{ID: "1", Name: "Jane"}))
+	assert.NoError(store.Save(ctx, &customerEntity{ID: "2", Name: "John"}))
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+
+	go func() {
+		done <- store.Transaction(ctx, func(txStore milo.Storer) error {
+			err := txStore.FindByIDForUpdate(ctx, &customerEntity{}, "1", false)
+			if err != nil {
+				return err
+			}
+
+			close(locked)
+			<-release
+
+			return txStore.Save(ctx, &customerEntity{ID: "1", Name: "Janet"})
+		})
+	}()
+
+	<-locked
+
+	// Locked rows are skipped with skipLocked.
+	entity := &customerEntity{}
+	err := store.FindOneByForUpdate(ctx, entity, true)
+	assert.NoError(err)
+	assert.Equal("2", entity.ID)
+
+	err = store.FindByIDForUpdate(ctx, entity, "1", true)
+	assert.EqualError(err, "entity not found: *memstore.customerEntity with ID 1 (FOR UPDATE SKIP LOCKED)")
+
+	// Finders that don't lock don't wait.
+	err = store.FindByID(ctx, entity, "1")
+	assert.NoError(err)
+	assert.Equal("Jane", entity.Name)
+
+	// Without skipLocked, the finder waits until the lock is released and finds the committed row.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	err = store.FindByIDForUpdate(timeoutCtx, entity, "1", false)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	waited := make(chan error)
+
+	go func() {
+		waited <- store.FindByIDForUpdate(ctx, entity, "1", false)
+	}()
+
+	close(release)
+	assert.NoError(<-done)
+	assert.NoError(<-waited)
+	assert.Equal("Janet", entity.Name)
+}
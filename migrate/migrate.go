@@ -0,0 +1,353 @@
+// Package migrate runs versioned schema migrations through the same orm.DB as milo.Store.
+//
+// Migrations are applied in version order and recorded in a tracking table. Each migration runs in its own transaction
+// (or in the caller's, if the Migrator was created with a *pg.Tx) that holds a transaction-level advisory lock, so
+// concurrent runners apply each migration once.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/pkg/errors"
+)
+
+const DefaultTableName = "milo_migrations"
+
+// Migration is a versioned schema change. Up and Down run in a transaction; db is that transaction.
+type Migration struct {
+	Version int64
+	Name    string
+
+	Up func(ctx context.Context, db orm.DB) error
+	// Down reverts Up. A migration without Down can't be rolled back.
+	Down func(ctx context.Context, db orm.DB) error
+}
+
+type Options struct {
+	// TableName is the name of the table that records the applied migrations. It defaults to DefaultTableName.
+	TableName string
+}
+
+// Status is the status of a migration.
+type Status struct {
+	Version int64
+	Name    string
+
+	// AppliedAt is nil if the migration is pending.
+	AppliedAt *time.Time
+	// Unknown is true if the migration is applied but isn't one of the Migrator's migrations, e.g., because it was
+	// applied by a newer version of the application.
+	Unknown bool
+}
+
+type Migrator struct {
+	db         orm.DB
+	migrations []*Migration
+	tableName  string
+}
+
+func NewMigrator(db orm.DB, migrations []*Migration, opts *Options) (*Migrator, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	tableName := opts.TableName
+	if tableName == "" {
+		tableName = DefaultTableName
+	}
+
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i, migration := range sorted {
+		if migration.Version <= 0 {
+			return nil, fmt.Errorf("migration %q: version must be positive", migration.Name)
+		}
+
+		if i > 0 && sorted[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version %d", sorted[i-1].Name, migration.Name, migration.Version)
+		}
+
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d has no up migration", migration.Version)
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		tableName:  tableName,
+	}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	for {
+		done, err := m.step(ctx, func(applied map[int64]*Status) (*Migration, bool, error) {
+			for _, migration := range m.migrations {
+				if _, ok := applied[migration.Version]; !ok {
+					return migration, true, nil
+				}
+			}
+
+			return nil, false, nil
+		})
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Down rolls back the latest applied migration. It does nothing if no migration is applied.
+func (m *Migrator) Down(ctx context.Context) error {
+	_, err := m.step(ctx, func(applied map[int64]*Status) (*Migration, bool, error) {
+		latest := latestApplied(applied, 0)
+		if latest == nil {
+			return nil, false, nil
+		}
+
+		migration, err := m.downMigration(latest)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return migration, false, nil
+	})
+
+	return err
+}
+
+// To applies or rolls back migrations until the applied migrations are exactly those with a version less than or
+// equal to version. To(ctx, 0) rolls back every migration.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	for {
+		done, err := m.step(ctx, func(applied map[int64]*Status) (*Migration, bool, error) {
+			// Roll back the newest migrations first, so that the migrations after the target are reverted in the
+			// opposite order they were applied.
+			if latest := latestApplied(applied, version); latest != nil {
+				migration, err := m.downMigration(latest)
+				if err != nil {
+					return nil, false, err
+				}
+
+				return migration, false, nil
+			}
+
+			for _, migration := range m.migrations {
+				if migration.Version > version {
+					break
+				}
+
+				if _, ok := applied[migration.Version]; !ok {
+					return migration, true, nil
+				}
+			}
+
+			return nil, false, nil
+		})
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Status returns the status of every migration, including unknown applied migrations, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var exists bool
+
+	// to_regclass parses its argument like an identifier in SQL, so it gets the name quoted like the table is created.
+	_, err := m.db.QueryOneContext(ctx, pg.Scan(&exists), "SELECT to_regclass(?) IS NOT NULL", string(types.AppendIdent(nil, m.tableName, 1)))
+	if err != nil {
+		return nil, errors.Wrap(err, "checking if migrations table exists")
+	}
+
+	applied := make(map[int64]*Status)
+
+	if exists {
+		applied, err = m.applied(ctx, m.db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []*Status
+
+	for _, migration := range m.migrations {
+		status := &Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		if s, ok := applied[migration.Version]; ok {
+			status.AppliedAt = s.AppliedAt
+			delete(applied, migration.Version)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, status := range applied {
+		status.Unknown = true
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// step runs next in a transaction that holds the migrations lock. next returns the migration to apply (up is true)
+// or roll back (up is false), or nil if there's nothing left to do. step returns true when there's nothing left to
+// do.
+func (m *Migrator) step(ctx context.Context, next func(applied map[int64]*Status) (migration *Migration, up bool, err error)) (bool, error) {
+	done := false
+
+	err := m.runInTransaction(ctx, func(tx orm.DB) error {
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(?)", m.lockKey())
+		if err != nil {
+			return errors.Wrap(err, "acquiring migrations lock")
+		}
+
+		_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS ? (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`, pg.Ident(m.tableName))
+		if err != nil {
+			return errors.Wrap(err, "creating migrations table")
+		}
+
+		// The applied migrations are read after acquiring the lock, so migrations applied by another runner in the
+		// meantime are skipped.
+		applied, err := m.applied(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		migration, up, err := next(applied)
+		if err != nil {
+			return err
+		}
+
+		if migration == nil {
+			done = true
+			return nil
+		}
+
+		if up {
+			err = migration.Up(ctx, tx)
+			if err != nil {
+				return errors.Wrapf(err, "applying migration %d (%s)", migration.Version, migration.Name)
+			}
+
+			_, err = tx.ExecContext(ctx, "INSERT INTO ? (version, name) VALUES (?, ?)", pg.Ident(m.tableName), migration.Version, migration.Name)
+			if err != nil {
+				return errors.Wrapf(err, "recording migration %d", migration.Version)
+			}
+
+			return nil
+		}
+
+		err = migration.Down(ctx, tx)
+		if err != nil {
+			return errors.Wrapf(err, "rolling back migration %d (%s)", migration.Version, migration.Name)
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM ? WHERE version = ?", pg.Ident(m.tableName), migration.Version)
+		if err != nil {
+			return errors.Wrapf(err, "recording rollback of migration %d", migration.Version)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+func (m *Migrator) runInTransaction(ctx context.Context, fn func(tx orm.DB) error) error {
+	if tx, ok := m.db.(*pg.Tx); ok {
+		return fn(tx)
+	}
+
+	return m.db.(*pg.DB).RunInTransaction(ctx, func(tx *pg.Tx) error {
+		return fn(tx)
+	})
+}
+
+// lockKey returns the key of the advisory lock, which depends on the table name so migrators of different tables
+// don't block each other.
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.tableName))
+
+	return int64(h.Sum64())
+}
+
+func (m *Migrator) applied(ctx context.Context, db orm.DB) (map[int64]*Status, error) {
+	var rows []struct {
+		Version   int64
+		Name      string
+		AppliedAt time.Time
+	}
+
+	_, err := db.QueryContext(ctx, &rows, "SELECT version, name, applied_at FROM ?", pg.Ident(m.tableName))
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting applied migrations")
+	}
+
+	applied := make(map[int64]*Status, len(rows))
+
+	for _, row := range rows {
+		appliedAt := row.AppliedAt
+
+		applied[row.Version] = &Status{
+			Version:   row.Version,
+			Name:      row.Name,
+			AppliedAt: &appliedAt,
+		}
+	}
+
+	return applied, nil
+}
+
+// latestApplied returns the applied migration with the highest version greater than after, or nil if there isn't one.
+func latestApplied(applied map[int64]*Status, after int64) *Status {
+	var latest *Status
+
+	for _, status := range applied {
+		if status.Version > after && (latest == nil || status.Version > latest.Version) {
+			latest = status
+		}
+	}
+
+	return latest
+}
+
+func (m *Migrator) downMigration(status *Status) (*Migration, error) {
+	for _, migration := range m.migrations {
+		if migration.Version != status.Version {
+			continue
+		}
+
+		if migration.Down == nil {
+			return nil, fmt.Errorf("migration %d (%s) can't be rolled back", migration.Version, migration.Name)
+		}
+
+		return migration, nil
+	}
+
+	return nil, fmt.Errorf("migration %d (%s) is applied but unknown", status.Version, status.Name)
+}
@@ -0,0 +1,261 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+func connect(t *testing.T) *pg.DB {
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	_, err := db.Exec(`DROP TABLE IF EXISTS migrate_test_migrations, "Migrate_Test_Migrations", migrate_test_accounts`)
+	if err != nil {
+		t.Fatalf("dropping tables: %v", err)
+	}
+
+	return db
+}
+
+func testMigrations(t *testing.T) []*Migration {
+	migrations, err := FromFS(fstest.MapFS{
+		"migrations/1_create_accounts.up.sql":   {Data: []byte("CREATE TABLE migrate_test_accounts (id text PRIMARY KEY);")},
+		"migrations/1_create_accounts.down.sql": {Data: []byte("DROP TABLE migrate_test_accounts;")},
+		"migrations/2_add_email.up.sql":         {Data: []byte("ALTER TABLE migrate_test_accounts ADD COLUMN email text; ALTER TABLE migrate_test_accounts ADD COLUMN verified bool;")},
+		"migrations/2_add_email.down.sql":       {Data: []byte("ALTER TABLE migrate_test_accounts DROP COLUMN email, DROP COLUMN verified;")},
+	}, "migrations")
+	if err != nil {
+		t.Fatalf("reading migrations: %v", err)
+	}
+
+	return append(migrations, &Migration{
+		Version: 3,
+		Name:    "seed_accounts",
+		Up: func(ctx context.Context, db orm.DB) error {
+			_, err := db.ExecContext(ctx, "INSERT INTO migrate_test_accounts (id, email) VALUES (?, ?)", "jane", "jane@example.com")
+			return err
+		},
+		Down: func(ctx context.Context, db orm.DB) error {
+			_, err := db.ExecContext(ctx, "DELETE FROM migrate_test_accounts WHERE id = ?", "jane")
+			return err
+		},
+	})
+}
+
+func versions(statuses []*Status) (applied []int64, pending []int64) {
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			applied = append(applied, status.Version)
+		} else {
+			pending = append(pending, status.Version)
+		}
+	}
+
+	return applied, pending
+}
+
+func TestMigrator(t *testing.T) {
+	assert := assert.New(t)
+
+	db := connect(t)
+	ctx := context.Background()
+
+	m, err := NewMigrator(db, testMigrations(t), &Options{TableName: "migrate_test_migrations"})
+	assert.NoError(err)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(err)
+
+	applied, pending := versions(statuses)
+	assert.Empty(applied)
+	assert.Equal([]int64{1, 2, 3}, pending)
+
+	err = m.Up(ctx)
+	assert.NoError(err)
+
+	statuses, err = m.Status(ctx)
+	assert.NoError(err)
+
+	applied, pending = versions(statuses)
+	assert.Equal([]int64{1, 2, 3}, applied)
+	assert.Empty(pending)
+
+	var count int
+	_, err = db.QueryOne(pg.Scan(&count), "SELECT count(*) FROM migrate_test_accounts")
+	assert.NoError(err)
+	assert.Equal(1, count)
+
+	err = m.Down(ctx)
+	assert.NoError(err)
+
+	statuses, err = m.Status(ctx)
+	assert.NoError(err)
+
+	applied, pending = versions(statuses)
+	assert.Equal([]int64{1, 2}, applied)
+	assert.Equal([]int64{3}, pending)
+
+	err = m.To(ctx, 1)
+	assert.NoError(err)
+
+	statuses, err = m.Status(ctx)
+	assert.NoError(err)
+
+	applied, pending = versions(statuses)
+	assert.Equal([]int64{1}, applied)
+	assert.Equal([]int64{2, 3}, pending)
+
+	err = m.To(ctx, 0)
+	assert.NoError(err)
+
+	_, err = db.Exec("SELECT 1 FROM migrate_test_accounts")
+	assert.Error(err, "table was dropped")
+
+	// An applied migration that the migrator doesn't know about can be reported but not rolled back.
+	err = m.Up(ctx)
+	assert.NoError(err)
+
+	older, err := NewMigrator(db, testMigrations(t)[:2], &Options{TableName: "migrate_test_migrations"})
+	assert.NoError(err)
+
+	statuses, err = older.Status(ctx)
+	assert.NoError(err)
+	assert.Len(statuses, 3)
+	assert.True(statuses[2].Unknown)
+	assert.Equal("seed_accounts", statuses[2].Name)
+
+	err = older.Down(ctx)
+	assert.EqualError(err, "migration 3 (seed_accounts) is applied but unknown")
+}
+
+func TestMigrator_TableName(t *testing.T) {
+	assert := assert.New(t)
+
+	db := connect(t)
+	ctx := context.Background()
+
+	// The table name is an identifier, so its case is kept and it can be qualified by a schema.
+	m, err := NewMigrator(db, testMigrations(t), &Options{TableName: "public.Migrate_Test_Migrations"})
+	assert.NoError(err)
+
+	err = m.Up(ctx)
+	assert.NoError(err)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(err)
+
+	applied, pending := versions(statuses)
+	assert.Equal([]int64{1, 2, 3}, applied)
+	assert.Empty(pending)
+}
+
+func TestMigrator_Failure(t *testing.T) {
+	assert := assert.New(t)
+
+	db := connect(t)
+	ctx := context.Background()
+
+	errFailed := errors.New("failed")
+
+	migrations := append(testMigrations(t)[:1], &Migration{
+		Version: 2,
+		Name:    "fail",
+		Up: func(ctx context.Context, db orm.DB) error {
+			_, err := db.ExecContext(ctx, "ALTER TABLE migrate_test_accounts ADD COLUMN email text")
+			if err != nil {
+				return err
+			}
+
+			return errFailed
+		},
+	})
+
+	m, err := NewMigrator(db, migrations, &Options{TableName: "migrate_test_migrations"})
+	assert.NoError(err)
+
+	err = m.Up(ctx)
+	assert.ErrorIs(err, errFailed)
+
+	// The failed migration was rolled back, the one before it wasn't.
+	statuses, err := m.Status(ctx)
+	assert.NoError(err)
+
+	applied, pending := versions(statuses)
+	assert.Equal([]int64{1}, applied)
+	assert.Equal([]int64{2}, pending)
+
+	_, err = db.Exec("SELECT email FROM migrate_test_accounts")
+	assert.Error(err)
+}
+
+func TestMigrator_Concurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	db := connect(t)
+	ctx := context.Background()
+
+	errs := make(chan error)
+
+	for i := 0; i < 3; i++ {
+		m, err := NewMigrator(db, testMigrations(t), &Options{TableName: "migrate_test_migrations"})
+		assert.NoError(err)
+
+		go func() {
+			errs <- m.Up(ctx)
+		}()
+	}
+
+	// Each migration is applied once, so none of them fails with "already exists".
+	for i := 0; i < 3; i++ {
+		assert.NoError(<-errs)
+	}
+}
+
+func TestNewMigrator(t *testing.T) {
+	up := func(ctx context.Context, db orm.DB) error {
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		migrations  []*Migration
+		expectedErr string
+	}{
+		{
+			name:        "zero version",
+			migrations:  []*Migration{{Name: "create_accounts", Up: up}},
+			expectedErr: `migration "create_accounts": version must be positive`,
+		},
+		{
+			name:        "duplicate version",
+			migrations:  []*Migration{{Version: 1, Name: "create_accounts", Up: up}, {Version: 1, Name: "add_email", Up: up}},
+			expectedErr: `migrations "create_accounts" and "add_email" have the same version 1`,
+		},
+		{
+			name:        "no up",
+			migrations:  []*Migration{{Version: 1, Name: "create_accounts"}},
+			expectedErr: "migration 1 has no up migration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMigrator(nil, tt.migrations, nil)
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/pkg/errors"
+)
+
+var sqlFileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// FromFS returns the migrations of the .sql files in dir of fsys, e.g., an embed.FS. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql; the down file is optional. Files can contain several
+// statements. Other files are ignored.
+//
+//	//go:embed migrations
+//	var migrations embed.FS
+//
+//	m, err := migrate.FromFS(migrations, "migrations")
+func FromFS(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations directory")
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		m := sqlFileRegexp.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %s must be named <version>_<name>.up.sql or <version>_<name>.down.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing version of migration file %s", entry.Name())
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading migration file %s", entry.Name())
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{
+				Version: version,
+				Name:    m[2],
+			}
+
+			byVersion[version] = migration
+		}
+
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration files of version %d have different names (%s and %s)", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.Up = execSQL(string(b))
+		} else {
+			migration.Down = execSQL(string(b))
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d (%s) has no up file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func execSQL(sql string) func(ctx context.Context, db orm.DB) error {
+	return func(ctx context.Context, db orm.DB) error {
+		// Without params, go-pg sends the query as is, so ? isn't a placeholder and the file can contain several
+		// statements.
+		_, err := db.ExecContext(ctx, sql)
+		return err
+	}
+}
@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromFS(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"migrations/002_add_email.up.sql":         {Data: []byte("ALTER TABLE accounts ADD COLUMN email text;")},
+		"migrations/001_create_accounts.up.sql":   {Data: []byte("CREATE TABLE accounts (id text PRIMARY KEY);")},
+		"migrations/001_create_accounts.down.sql": {Data: []byte("DROP TABLE accounts;")},
+		"migrations/README.md":                    {Data: []byte("Migrations")},
+	}
+
+	migrations, err := FromFS(fsys, "migrations")
+	assert.NoError(err)
+	assert.Len(migrations, 2)
+
+	assert.Equal(int64(1), migrations[0].Version)
+	assert.Equal("create_accounts", migrations[0].Name)
+	assert.NotNil(migrations[0].Up)
+	assert.NotNil(migrations[0].Down)
+
+	assert.Equal(int64(2), migrations[1].Version)
+	assert.Equal("add_email", migrations[1].Name)
+	assert.NotNil(migrations[1].Up)
+	assert.Nil(migrations[1].Down)
+}
+
+func TestFromFS_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		fsys        fstest.MapFS
+		expectedErr string
+	}{
+		{
+			name: "bad name",
+			fsys: fstest.MapFS{
+				"migrations/create_accounts.sql": {},
+			},
+			expectedErr: "migration file create_accounts.sql must be named <version>_<name>.up.sql or <version>_<name>.down.sql",
+		},
+		{
+			name: "no up file",
+			fsys: fstest.MapFS{
+				"migrations/001_create_accounts.down.sql": {},
+			},
+			expectedErr: "migration 1 (create_accounts) has no up file",
+		},
+		{
+			name: "different names",
+			fsys: fstest.MapFS{
+				"migrations/001_create_accounts.up.sql":  {},
+				"migrations/001_create_customers.up.sql": {},
+			},
+			expectedErr: "migration files of version 1 have different names (create_accounts and create_customers)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromFS(tt.fsys, "migrations")
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
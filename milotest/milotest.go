@@ -0,0 +1,35 @@
+// Package milotest provides helpers for tests that use milo.
+package milotest
+
+import (
+	"testing"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/go-pg/pg/v10"
+)
+
+// NewTxStore returns a store for a transaction of db that is rolled back when t and its subtests finish, so tests can
+// share a database (and run in parallel) without seeing each other's writes. Store.Transaction, Save and Delete run
+// in a savepoint of the transaction (see milo.NewSavepointStore), so a test can expect a write to fail and go on.
+func NewTxStore(t testing.TB, db *pg.DB, entityModelMap milo.EntityModelMap) *milo.Store {
+	t.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		err := tx.Rollback()
+		if err != nil {
+			t.Errorf("rolling back transaction: %v", err)
+		}
+	})
+
+	store, err := milo.NewSavepointStore(tx, entityModelMap)
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+
+	return store
+}
@@ -0,0 +1,210 @@
+package milotest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type customer struct {
+	tableName struct{} `pg:"milotest_customers"`
+
+	ID   string `pg:"id"`
+	Name string `pg:"name"`
+}
+
+var _ milo.Model = (*customer)(nil)
+
+// customer is its own entity.
+func (c *customer) FromEntity(entity interface{}) error {
+	*c = *entity.(*customer)
+
+	return nil
+}
+
+func (c *customer) ToEntity() (interface{}, error) {
+	entity := *c
+
+	return &entity, nil
+}
+
+var entityModelMap = milo.EntityModelMap{
+	reflect.TypeOf(&customer{}): reflect.TypeOf(&customer{}),
+}
+
+func connect(t *testing.T) *pg.DB {
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	err := db.Model((*customer)(nil)).CreateTable(&orm.CreateTableOptions{
+		IfNotExists: true,
+	})
+	if err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+
+	return db
+}
+
+func TestNewTxStore(t *testing.T) {
+	db := connect(t)
+	ctx := context.Background()
+
+	var ids []string
+
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"Jane", "John"} {
+			name := name
+			id := uuid.New().String()
+			ids = append(ids, id)
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				assert := assert.New(t)
+
+				store := NewTxStore(t, db, entityModelMap)
+
+				err := store.Save(ctx, &customer{ID: id, Name: name})
+				assert.NoError(err)
+
+				// Each test only sees its own writes.
+				var all []*customer
+				err = store.FindBy(ctx, &all, milo.Equal("name", name))
+				assert.NoError(err)
+				assert.Len(all, 1)
+			})
+		}
+	})
+
+	// Everything was rolled back.
+	for _, id := range ids {
+		exists, err := db.Model((*customer)(nil)).Where("id = ?", id).Exists()
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	}
+}
+
+func TestNewTxStore_Transaction(t *testing.T) {
+	assert := assert.New(t)
+
+	db := connect(t)
+	ctx := context.Background()
+
+	store := NewTxStore(t, db, entityModelMap)
+
+	jane := &customer{ID: uuid.New().String(), Name: "Jane"}
+	john := &customer{ID: uuid.New().String(), Name: "John"}
+	sally := &customer{ID: uuid.New().String(), Name: "Sally"}
+
+	errRollback := errors.New("rollback")
+
+	err := store.Transaction(ctx, func(txStore milo.Storer) error {
+		err := txStore.Save(ctx, jane)
+		if err != nil {
+			return err
+		}
+
+		// A nested transaction is rolled back to its own savepoint.
+		err = txStore.Transaction(ctx, func(txStore milo.Storer) error {
+			err := txStore.Save(ctx, john)
+			if err != nil {
+				return err
+			}
+
+			return errRollback
+		})
+		assert.ErrorIs(err, errRollback)
+
+		return nil
+	})
+	assert.NoError(err)
+
+	err = store.Transaction(ctx, func(txStore milo.Storer) error {
+		err := txStore.Save(ctx, sally)
+		if err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(err, errRollback)
+
+	err = store.FindByID(ctx, &customer{}, jane.ID)
+	assert.NoError(err)
+
+	err = store.FindByID(ctx, &customer{}, john.ID)
+	assert.ErrorIs(err, milo.ErrNotFound)
+
+	err = store.FindByID(ctx, &customer{}, sally.ID)
+	assert.ErrorIs(err, milo.ErrNotFound)
+}
+
+type account struct {
+	tableName struct{} `pg:"milotest_accounts"`
+
+	ID    string `pg:"id"`
+	Email string `pg:"email,unique"`
+}
+
+var _ milo.Model = (*account)(nil)
+
+// account is its own entity.
+func (a *account) FromEntity(entity interface{}) error {
+	*a = *entity.(*account)
+
+	return nil
+}
+
+func (a *account) ToEntity() (interface{}, error) {
+	entity := *a
+
+	return &entity, nil
+}
+
+func TestNewTxStore_FailedWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	db := connect(t)
+	ctx := context.Background()
+
+	err := db.Model((*account)(nil)).CreateTable(&orm.CreateTableOptions{
+		IfNotExists: true,
+	})
+	if err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+
+	store := NewTxStore(t, db, milo.EntityModelMap{
+		reflect.TypeOf(&account{}): reflect.TypeOf(&account{}),
+	})
+
+	email := uuid.New().String() + "@example.com"
+
+	jane := &account{ID: uuid.New().String(), Email: email}
+
+	err = store.Save(ctx, jane)
+	assert.NoError(err)
+
+	err = store.Save(ctx, &account{ID: uuid.New().String(), Email: email})
+	assert.ErrorIs(err, milo.ErrUniqueViolation)
+
+	// The failed save was rolled back to its savepoint, so the transaction can still be used.
+	err = store.FindByID(ctx, &account{}, jane.ID)
+	assert.NoError(err)
+}
@@ -0,0 +1,424 @@
+package milotest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/pkg/errors"
+)
+
+// RoundTripOptions configures AssertRoundTrip.
+type RoundTripOptions struct {
+	// Iterations is the number of random entities checked per entity type. It defaults to 20.
+	Iterations int
+	// Seed seeds the random entities. It defaults to the current time and is reported when a check fails, so the
+	// failure can be reproduced.
+	Seed int64
+	// Generators generate the values of the given types instead of random values, e.g., for types with invariants.
+	Generators map[reflect.Type]func(r *rand.Rand) interface{}
+	// Ignore lists the field paths (e.g., "CreatedAt" or "Addresses[*].ID") that aren't expected to survive the round
+	// trip. The fields inside an ignored field are ignored too.
+	Ignore []string
+}
+
+// FieldDiff is a field that didn't survive the round trip.
+type FieldDiff struct {
+	// Path is the path of the field in the entity, e.g., "Addresses[0].ID".
+	Path     string
+	Expected interface{}
+	Actual   interface{}
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.Path, formatValue(d.Expected), formatValue(d.Actual))
+}
+
+// formatValue formats v like %#v, but shows the values of pointers instead of their addresses.
+func formatValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		return "&" + formatValue(rv.Elem().Interface())
+	}
+
+	return fmt.Sprintf("%#v", v)
+}
+
+// AssertRoundTrip checks that the entities of entityModelMap survive a round trip through their model: random
+// entities are converted to a model with FromEntity and back with ToEntity, and the result is compared with the
+// original entity. The check fails with the paths of the fields that differ. Random values are never the zero value
+// of their type, so a field that is dropped in either direction is found. opts may be nil.
+func AssertRoundTrip(t testing.TB, entityModelMap milo.EntityModelMap, opts *RoundTripOptions) bool {
+	t.Helper()
+
+	if opts == nil {
+		opts = &RoundTripOptions{}
+	}
+
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = 20
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	entityTypes := make([]reflect.Type, 0, len(entityModelMap))
+	for entityType := range entityModelMap {
+		entityTypes = append(entityTypes, entityType)
+	}
+
+	sort.Slice(entityTypes, func(i, j int) bool {
+		return entityTypes[i].String() < entityTypes[j].String()
+	})
+
+	ok := true
+
+	for _, entityType := range entityTypes {
+		r := rand.New(rand.NewSource(seed))
+
+		for i := 0; i < iterations; i++ {
+			entity := RandomEntity(entityType, r, opts.Generators)
+
+			diffs, err := RoundTripDiff(entityModelMap, entity)
+			if err != nil {
+				t.Errorf("round trip of %s (seed %d): %v", entityType.String(), seed, err)
+				ok = false
+
+				break
+			}
+
+			diffs = ignoreDiffs(diffs, opts.Ignore)
+
+			if len(diffs) > 0 {
+				lines := make([]string, len(diffs))
+				for i, diff := range diffs {
+					lines[i] = "\t" + diff.String()
+				}
+
+				t.Errorf("fields of %s didn't survive the round trip (seed %d):\n%s", entityType.String(), seed, strings.Join(lines, "\n"))
+				ok = false
+
+				break
+			}
+		}
+	}
+
+	return ok
+}
+
+// RoundTripDiff converts entity, an entity pointer, to its model with FromEntity and back with ToEntity, and returns
+// the fields of entity that differ from the result.
+func RoundTripDiff(entityModelMap milo.EntityModelMap, entity interface{}) ([]FieldDiff, error) {
+	entityType := reflect.TypeOf(entity)
+
+	modelType, ok := entityModelMap[entityType]
+	if !ok {
+		return nil, fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	model := reflect.New(modelType.Elem()).Interface().(milo.Model)
+
+	err := model.FromEntity(entity)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting entity to model")
+	}
+
+	result, err := model.ToEntity()
+	if err != nil {
+		return nil, errors.Wrap(err, "converting model to entity")
+	}
+
+	if reflect.TypeOf(result) != entityType {
+		return nil, fmt.Errorf("ToEntity returned %T, expected %s", result, entityType.String())
+	}
+
+	var diffs []FieldDiff
+	diffValues("", reflect.ValueOf(entity).Elem(), reflect.ValueOf(result).Elem(), &diffs)
+
+	return diffs, nil
+}
+
+// maxRandomDepth limits how deep RandomEntity follows pointers and structs, so recursive types end.
+const maxRandomDepth = 6
+
+// RandomEntity returns a pointer of type entityType to a random entity. Exported fields are set to random non-zero
+// values: strings, numbers, times (in UTC, truncated to microseconds like Postgres timestamps), and pointers, slices
+// and maps with one to three elements. Values of the types in generators are generated by those functions instead.
+// Interfaces, funcs and channels are left nil.
+func RandomEntity(entityType reflect.Type, r *rand.Rand, generators map[reflect.Type]func(r *rand.Rand) interface{}) interface{} {
+	v := reflect.New(entityType).Elem()
+	randomValue(v, r, generators, 0)
+
+	return v.Interface()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func randomValue(v reflect.Value, r *rand.Rand, generators map[reflect.Type]func(r *rand.Rand) interface{}, depth int) {
+	if generate, ok := generators[v.Type()]; ok {
+		v.Set(reflect.ValueOf(generate(r)))
+		return
+	}
+
+	if v.Type() == timeType {
+		t := time.Date(2000+r.Intn(50), time.Month(1+r.Intn(12)), 1+r.Intn(28), r.Intn(24), r.Intn(60), r.Intn(60), r.Intn(1e6)*1e3, time.UTC)
+		v.Set(reflect.ValueOf(t))
+
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if depth >= maxRandomDepth {
+			return
+		}
+
+		p := reflect.New(v.Type().Elem())
+		randomValue(p.Elem(), r, generators, depth+1)
+		v.Set(p)
+
+	case reflect.Struct:
+		if depth >= maxRandomDepth {
+			return
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				randomValue(v.Field(i), r, generators, depth+1)
+			}
+		}
+
+	case reflect.Slice:
+		if depth >= maxRandomDepth {
+			return
+		}
+
+		n := 1 + r.Intn(3)
+		s := reflect.MakeSlice(v.Type(), n, n)
+
+		for i := 0; i < n; i++ {
+			randomValue(s.Index(i), r, generators, depth+1)
+		}
+
+		v.Set(s)
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			randomValue(v.Index(i), r, generators, depth+1)
+		}
+
+	case reflect.Map:
+		if depth >= maxRandomDepth {
+			return
+		}
+
+		m := reflect.MakeMap(v.Type())
+
+		for i := 1 + r.Intn(3); i > 0; i-- {
+			key := reflect.New(v.Type().Key()).Elem()
+			randomValue(key, r, generators, depth+1)
+
+			value := reflect.New(v.Type().Elem()).Elem()
+			randomValue(value, r, generators, depth+1)
+
+			m.SetMapIndex(key, value)
+		}
+
+		v.Set(m)
+
+	case reflect.String:
+		v.SetString(randomString(r))
+
+	case reflect.Bool:
+		v.SetBool(true)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Small values fit every int type and int64s can be stored in float64 jsonb numbers exactly.
+		v.SetInt(1 + r.Int63n(100))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(1 + uint64(r.Int63n(100)))
+
+	case reflect.Float32, reflect.Float64:
+		// Quarters are exact in float32 and float64.
+		v.SetFloat(float64(1+r.Intn(400)) / 4)
+
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(complex(float64(1+r.Intn(100)), float64(1+r.Intn(100))))
+	}
+}
+
+const randomStringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomString(r *rand.Rand) string {
+	b := make([]byte, 4+r.Intn(9))
+	for i := range b {
+		b[i] = randomStringChars[r.Intn(len(randomStringChars))]
+	}
+
+	return string(b)
+}
+
+// diffValues appends the paths at which a and b differ to diffs. Unexported fields and funcs are ignored.
+func diffValues(path string, a reflect.Value, b reflect.Value, diffs *[]FieldDiff) {
+	appendDiff := func() {
+		*diffs = append(*diffs, FieldDiff{
+			Path:     path,
+			Expected: valueInterface(a),
+			Actual:   valueInterface(b),
+		})
+	}
+
+	if a.Type() == timeType {
+		if !a.Interface().(time.Time).Equal(b.Interface().(time.Time)) {
+			appendDiff()
+		}
+
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				appendDiff()
+			}
+
+			return
+		}
+
+		if a.Kind() == reflect.Interface && a.Elem().Type() != b.Elem().Type() {
+			appendDiff()
+			return
+		}
+
+		diffValues(path, a.Elem(), b.Elem(), diffs)
+
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			diffValues(joinPath(path, field.Name), a.Field(i), b.Field(i), diffs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			appendDiff()
+			return
+		}
+
+		for i := 0; i < a.Len(); i++ {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), diffs)
+		}
+
+	case reflect.Map:
+		keys := a.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, key := range keys {
+			keyPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+
+			bv := b.MapIndex(key)
+			if !bv.IsValid() {
+				*diffs = append(*diffs, FieldDiff{
+					Path:     keyPath,
+					Expected: valueInterface(a.MapIndex(key)),
+				})
+
+				continue
+			}
+
+			diffValues(keyPath, a.MapIndex(key), bv, diffs)
+		}
+
+		if b.Len() > a.Len() {
+			for _, key := range b.MapKeys() {
+				if !a.MapIndex(key).IsValid() {
+					*diffs = append(*diffs, FieldDiff{
+						Path:   fmt.Sprintf("%s[%v]", path, key.Interface()),
+						Actual: valueInterface(b.MapIndex(key)),
+					})
+				}
+			}
+		}
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return
+
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			appendDiff()
+		}
+	}
+}
+
+func joinPath(path string, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+var pathIndexRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
+// ignoreDiffs removes the diffs at or inside the ignored paths. [*] in an ignored path matches any index or key.
+func ignoreDiffs(diffs []FieldDiff, ignore []string) []FieldDiff {
+	if len(ignore) == 0 {
+		return diffs
+	}
+
+	var kept []FieldDiff
+
+	for _, diff := range diffs {
+		wildcardPath := pathIndexRegexp.ReplaceAllString(diff.Path, "[*]")
+
+		ignored := false
+
+		for _, pattern := range ignore {
+			if hasPathPrefix(diff.Path, pattern) || hasPathPrefix(wildcardPath, pattern) {
+				ignored = true
+				break
+			}
+		}
+
+		if !ignored {
+			kept = append(kept, diff)
+		}
+	}
+
+	return kept
+}
+
+// hasPathPrefix returns whether path is prefix or a field, index or key inside it.
+func hasPathPrefix(path string, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	rest := path[len(prefix):]
+
+	return len(rest) == 0 || rest[0] == '.' || rest[0] == '['
+}
@@ -0,0 +1,218 @@
+package milotest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderID string
+
+type orderEntity struct {
+	ID        orderID
+	Reference uuid.UUID
+	Total     float64
+	Paid      bool
+	Notes     *string
+	Metadata  map[string]string
+	CreatedAt time.Time
+
+	Items []*itemEntity
+}
+
+type itemEntity struct {
+	ID       string
+	SKU      string
+	Quantity int
+}
+
+type orderModel struct {
+	tableName struct{} `pg:"orders"`
+
+	ID        string            `pg:"id"`
+	Reference uuid.UUID         `pg:"reference,type:uuid"`
+	Total     float64           `pg:"total"`
+	Paid      bool              `pg:"paid"`
+	Notes     *string           `pg:"notes"`
+	Metadata  map[string]string `pg:"metadata,type:jsonb"`
+	CreatedAt time.Time         `pg:"created_at"`
+
+	Items []*itemModel `pg:"rel:has-many,join_fk:order_id"`
+}
+
+type itemModel struct {
+	tableName struct{} `pg:"items"`
+
+	ID       string `pg:"id"`
+	OrderID  string `pg:"order_id"`
+	SKU      string `pg:"sku"`
+	Quantity int16  `pg:"quantity"`
+}
+
+func (o *orderModel) FromEntity(e interface{}) error {
+	entity := e.(*orderEntity)
+
+	o.ID = string(entity.ID)
+	o.Reference = entity.Reference
+	o.Total = entity.Total
+	o.Paid = entity.Paid
+	o.Notes = entity.Notes
+	o.Metadata = entity.Metadata
+	o.CreatedAt = entity.CreatedAt
+
+	for _, item := range entity.Items {
+		o.Items = append(o.Items, &itemModel{
+			ID:       item.ID,
+			OrderID:  string(entity.ID),
+			SKU:      item.SKU,
+			Quantity: int16(item.Quantity),
+		})
+	}
+
+	return nil
+}
+
+func (o *orderModel) ToEntity() (interface{}, error) {
+	entity := &orderEntity{
+		ID:        orderID(o.ID),
+		Reference: o.Reference,
+		Total:     o.Total,
+		Paid:      o.Paid,
+		Notes:     o.Notes,
+		Metadata:  o.Metadata,
+		CreatedAt: o.CreatedAt,
+	}
+
+	for _, item := range o.Items {
+		entity.Items = append(entity.Items, &itemEntity{
+			ID:       item.ID,
+			SKU:      item.SKU,
+			Quantity: int(item.Quantity),
+		})
+	}
+
+	return entity, nil
+}
+
+// buggyOrderModel forgets Notes in FromEntity and the item IDs in ToEntity.
+type buggyOrderModel struct {
+	orderModel
+}
+
+func (o *buggyOrderModel) FromEntity(e interface{}) error {
+	err := o.orderModel.FromEntity(e)
+	o.Notes = nil
+
+	return err
+}
+
+func (o *buggyOrderModel) ToEntity() (interface{}, error) {
+	entity, err := o.orderModel.ToEntity()
+
+	for _, item := range entity.(*orderEntity).Items {
+		item.ID = ""
+	}
+
+	return entity, err
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	AssertRoundTrip(t, milo.EntityModelMap{
+		reflect.TypeOf(&orderEntity{}): reflect.TypeOf(&orderModel{}),
+	}, nil)
+
+	AssertRoundTrip(t, milo.EntityModelMap{
+		reflect.TypeOf(&orderEntity{}): reflect.TypeOf(&buggyOrderModel{}),
+	}, &RoundTripOptions{
+		Ignore: []string{"Notes", "Items[*].ID"},
+	})
+}
+
+func TestRoundTripDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	entityModelMap := milo.EntityModelMap{
+		reflect.TypeOf(&orderEntity{}): reflect.TypeOf(&buggyOrderModel{}),
+	}
+
+	entity := RandomEntity(reflect.TypeOf(&orderEntity{}), rand.New(rand.NewSource(1)), nil).(*orderEntity)
+
+	diffs, err := RoundTripDiff(entityModelMap, entity)
+	assert.NoError(err)
+
+	var paths []string
+	for _, diff := range diffs {
+		paths = append(paths, diff.Path)
+	}
+
+	expected := []string{"Notes"}
+	for i := range entity.Items {
+		expected = append(expected, "Items["+string(rune('0'+i))+"].ID")
+	}
+
+	assert.Equal(expected, paths)
+	assert.Equal(entity.Notes, diffs[0].Expected)
+	assert.Equal((*string)(nil), diffs[0].Actual)
+
+	_, err = RoundTripDiff(entityModelMap, &itemEntity{})
+	assert.EqualError(err, "unable to find model type for entity type *milotest.itemEntity")
+}
+
+func TestRandomEntity(t *testing.T) {
+	assert := assert.New(t)
+
+	generators := map[reflect.Type]func(r *rand.Rand) interface{}{
+		reflect.TypeOf(orderID("")): func(r *rand.Rand) interface{} {
+			return orderID("order-1")
+		},
+	}
+
+	entity := RandomEntity(reflect.TypeOf(&orderEntity{}), rand.New(rand.NewSource(1)), generators).(*orderEntity)
+
+	assert.Equal(orderID("order-1"), entity.ID)
+	assert.NotEqual(uuid.Nil, entity.Reference)
+	assert.NotZero(entity.Total)
+	assert.True(entity.Paid)
+	assert.NotNil(entity.Notes)
+	assert.NotEmpty(entity.Metadata)
+	assert.Equal(time.UTC, entity.CreatedAt.Location())
+	assert.Equal(entity.CreatedAt, entity.CreatedAt.Truncate(time.Microsecond))
+	assert.NotEmpty(entity.Items)
+
+	for _, item := range entity.Items {
+		assert.NotEmpty(item.ID)
+		assert.NotZero(item.Quantity)
+	}
+
+	// The same seed generates the same entity.
+	assert.Equal(entity, RandomEntity(reflect.TypeOf(&orderEntity{}), rand.New(rand.NewSource(1)), generators))
+}
+
+func TestIgnoreDiffs(t *testing.T) {
+	assert := assert.New(t)
+
+	diffs := []FieldDiff{
+		{Path: "Name"},
+		{Path: "NameLast"},
+		{Path: "Items[0].ID"},
+		{Path: "Items[1].SKU"},
+		{Path: "Profile.About"},
+	}
+
+	kept := ignoreDiffs(diffs, []string{"Name", "Items[*].ID", "Profile"})
+	assert.Equal([]FieldDiff{{Path: "NameLast"}, {Path: "Items[1].SKU"}}, kept)
+}
+
+func TestFieldDiff_String(t *testing.T) {
+	assert := assert.New(t)
+
+	notes := "fragile"
+
+	assert.Equal(`Notes: expected &"fragile", got (*string)(nil)`, FieldDiff{Path: "Notes", Expected: &notes, Actual: (*string)(nil)}.String())
+	assert.Equal(`Items[0].Quantity: expected 2, got 0`, FieldDiff{Path: "Items[0].Quantity", Expected: 2, Actual: 0}.String())
+}
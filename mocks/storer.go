@@ -0,0 +1,161 @@
+// Package mocks provides a testify mock of milo.Storer.
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/stretchr/testify/mock"
+)
+
+// Storer is a mock of milo.Storer. Expressions passed to the finders are passed to Called as separate arguments, so
+// expectations can match them one by one.
+type Storer struct {
+	mock.Mock
+}
+
+var _ milo.Storer = (*Storer)(nil)
+
+// Transaction returns the error of the expectation. If the expectation returns a
+// func(context.Context, func(milo.Storer) error) error, it is called instead (see OnTransaction).
+func (m *Storer) Transaction(ctx context.Context, fn func(txStore milo.Storer) error) error {
+	ret := m.Called(ctx, fn)
+
+	if f, ok := ret.Get(0).(func(context.Context, func(milo.Storer) error) error); ok {
+		return f(ctx, fn)
+	}
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindAll(ctx context.Context, entities interface{}) error {
+	ret := m.Called(ctx, entities)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindBy(ctx context.Context, entities interface{}, exprs ...milo.Expression) error {
+	ret := m.Called(append([]interface{}{ctx, entities}, exprArgs(exprs)...)...)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindByForUpdate(ctx context.Context, entities interface{}, skipLocked bool, exprs ...milo.Expression) error {
+	ret := m.Called(append([]interface{}{ctx, entities, skipLocked}, exprArgs(exprs)...)...)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindOneBy(ctx context.Context, entity interface{}, exprs ...milo.Expression) error {
+	ret := m.Called(append([]interface{}{ctx, entity}, exprArgs(exprs)...)...)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindOneByForUpdate(ctx context.Context, entity interface{}, skipLocked bool, exprs ...milo.Expression) error {
+	ret := m.Called(append([]interface{}{ctx, entity, skipLocked}, exprArgs(exprs)...)...)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindByID(ctx context.Context, entity interface{}, id interface{}) error {
+	ret := m.Called(ctx, entity, id)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) FindByIDForUpdate(ctx context.Context, entity interface{}, id interface{}, skipLocked bool) error {
+	ret := m.Called(ctx, entity, id, skipLocked)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) Save(ctx context.Context, entity interface{}) error {
+	ret := m.Called(ctx, entity)
+
+	return ret.Error(0)
+}
+
+func (m *Storer) Delete(ctx context.Context, entity interface{}) error {
+	ret := m.Called(ctx, entity)
+
+	return ret.Error(0)
+}
+
+func exprArgs(exprs []milo.Expression) []interface{} {
+	args := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		args[i] = expr
+	}
+
+	return args
+}
+
+// OnTransaction expects a call to Transaction that runs fn with m as the transaction's store and returns the error of
+// fn.
+func (m *Storer) OnTransaction() *mock.Call {
+	return m.On("Transaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(milo.Storer) error) error {
+		return fn(m)
+	})
+}
+
+// OnFindByID expects a call to FindByID for id with an entity pointer of the same type as entity, and fills the
+// entity pointer with entity.
+func (m *Storer) OnFindByID(id interface{}, entity interface{}) *mock.Call {
+	return m.On("FindByID", mock.Anything, mock.AnythingOfType(reflect.TypeOf(entity).String()), id).Run(SetEntity(entity)).Return(nil)
+}
+
+// OnFindOneBy expects a call to FindOneBy with exprs and an entity pointer of the same type as entity, and fills the
+// entity pointer with entity.
+func (m *Storer) OnFindOneBy(entity interface{}, exprs ...milo.Expression) *mock.Call {
+	args := append([]interface{}{mock.Anything, mock.AnythingOfType(reflect.TypeOf(entity).String())}, exprArgs(exprs)...)
+
+	return m.On("FindOneBy", args...).Run(SetEntity(entity)).Return(nil)
+}
+
+// OnFindBy expects a call to FindBy with exprs and a pointer to a slice of the same type as entities, and appends
+// entities to the slice.
+func (m *Storer) OnFindBy(entities interface{}, exprs ...milo.Expression) *mock.Call {
+	args := append([]interface{}{mock.Anything, mock.AnythingOfType("*" + reflect.TypeOf(entities).String())}, exprArgs(exprs)...)
+
+	return m.On("FindBy", args...).Run(AppendEntities(entities)).Return(nil)
+}
+
+// OnFindAll expects a call to FindAll with a pointer to a slice of the same type as entities, and appends entities to
+// the slice.
+func (m *Storer) OnFindAll(entities interface{}) *mock.Call {
+	return m.On("FindAll", mock.Anything, mock.AnythingOfType("*"+reflect.TypeOf(entities).String())).Run(AppendEntities(entities)).Return(nil)
+}
+
+// SetEntity returns a function for mock.Call.Run that sets the entity pointer passed to FindByID, FindOneBy or their
+// ForUpdate variants to entity, an entity pointer of the same type. The entity passed to the store gets a copy of
+// *entity.
+func SetEntity(entity interface{}) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		dst := reflect.ValueOf(args.Get(1))
+		src := reflect.ValueOf(entity)
+
+		if dst.Type() != src.Type() || dst.Kind() != reflect.Ptr {
+			panic(fmt.Sprintf("mocks: can't set entity %s to %s", dst.Type().String(), src.Type().String()))
+		}
+
+		dst.Elem().Set(src.Elem())
+	}
+}
+
+// AppendEntities returns a function for mock.Call.Run that appends entities, a slice of entity pointers, to the slice
+// pointer passed to FindAll, FindBy or FindByForUpdate.
+func AppendEntities(entities interface{}) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		dst := reflect.ValueOf(args.Get(1))
+		src := reflect.ValueOf(entities)
+
+		if dst.Kind() != reflect.Ptr || dst.Type().Elem() != src.Type() {
+			panic(fmt.Sprintf("mocks: can't append %s to %s", src.Type().String(), dst.Type().String()))
+		}
+
+		dst.Elem().Set(reflect.AppendSlice(dst.Elem(), src))
+	}
+}
@@ -0,0 +1,137 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eleanorhealth/milo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type customer struct {
+	ID   string
+	Name string
+}
+
+func TestStorer_FindByID(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Storer{}
+	m.OnFindByID("1", &customer{ID: "1", Name: "Jane"}).Once()
+
+	entity := &customer{}
+	err := m.FindByID(context.Background(), entity, "1")
+	assert.NoError(err)
+	assert.Equal(&customer{ID: "1", Name: "Jane"}, entity)
+
+	m.AssertExpectations(t)
+}
+
+func TestStorer_FindOneBy(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Storer{}
+	m.OnFindOneBy(&customer{ID: "1", Name: "Jane"}, milo.Equal("name", "Jane")).Once()
+
+	entity := &customer{}
+	err := m.FindOneBy(context.Background(), entity, milo.Equal("name", "Jane"))
+	assert.NoError(err)
+	assert.Equal("1", entity.ID)
+
+	m.AssertExpectations(t)
+}
+
+func TestStorer_FindBy(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Storer{}
+	m.OnFindBy([]*customer{{ID: "1"}, {ID: "2"}}, milo.Gt("age", 30), milo.OrderBy("age")).Once()
+
+	var entities []*customer
+	err := m.FindBy(context.Background(), &entities, milo.Gt("age", 30), milo.OrderBy("age"))
+	assert.NoError(err)
+	assert.Len(entities, 2)
+	assert.Equal("2", entities[1].ID)
+
+	m.AssertExpectations(t)
+}
+
+func TestStorer_FindAll(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Storer{}
+	m.OnFindAll([]*customer{{ID: "1"}}).Once()
+
+	var entities []*customer
+	err := m.FindAll(context.Background(), &entities)
+	assert.NoError(err)
+	assert.Len(entities, 1)
+
+	m.AssertExpectations(t)
+}
+
+func TestStorer_ForUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Storer{}
+	m.On("FindByIDForUpdate", mock.Anything, mock.AnythingOfType("*mocks.customer"), "1", true).
+		Run(SetEntity(&customer{ID: "1"})).
+		Return(nil).
+		Once()
+	m.On("FindByForUpdate", mock.Anything, mock.AnythingOfType("*[]*mocks.customer"), false, milo.IsNull("name")).
+		Run(AppendEntities([]*customer{{ID: "2"}})).
+		Return(nil).
+		Once()
+
+	entity := &customer{}
+	err := m.FindByIDForUpdate(context.Background(), entity, "1", true)
+	assert.NoError(err)
+	assert.Equal("1", entity.ID)
+
+	var entities []*customer
+	err = m.FindByForUpdate(context.Background(), &entities, false, milo.IsNull("name"))
+	assert.NoError(err)
+	assert.Len(entities, 1)
+
+	m.AssertExpectations(t)
+}
+
+func TestStorer_Transaction(t *testing.T) {
+	assert := assert.New(t)
+
+	errTest := errors.New("test")
+
+	m := &Storer{}
+	m.OnTransaction().Once()
+	m.On("Save", mock.Anything, mock.AnythingOfType("*mocks.customer")).Return(errTest).Once()
+
+	err := m.Transaction(context.Background(), func(txStore milo.Storer) error {
+		assert.Same(m, txStore)
+
+		return txStore.Save(context.Background(), &customer{})
+	})
+	assert.ErrorIs(err, errTest)
+
+	m.On("Transaction", mock.Anything, mock.Anything).Return(errTest).Once()
+
+	err = m.Transaction(context.Background(), func(txStore milo.Storer) error {
+		return nil
+	})
+	assert.ErrorIs(err, errTest)
+
+	m.AssertExpectations(t)
+}
+
+func TestSetEntity(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Panics(func() {
+		SetEntity(&customer{})(mock.Arguments{context.Background(), &struct{}{}})
+	})
+
+	assert.Panics(func() {
+		AppendEntities([]*customer{})(mock.Arguments{context.Background(), &[]customer{}})
+	})
+}
@@ -11,3 +11,35 @@ type Hook interface {
 	BeforeSave(ctx context.Context, store Storer, entity interface{}) error
 	BeforeDelete(ctx context.Context, store Storer, entity interface{}) error
 }
+
+// Indexer is implemented by models that declare indexes of their table. CreateSchema creates them, DetectSchemaDrift
+// reports the missing ones and the DBError of a unique violation names the one a write violated.
+type Indexer interface {
+	Indexes() []*Index
+}
+
+// Checker is implemented by models that declare check constraints of their table. CreateSchema creates them and
+// DetectSchemaDrift reports the missing ones.
+type Checker interface {
+	Checks() []*Check
+}
+
+type Index struct {
+	// Name defaults to <table>_<columns>_idx, or <table>_<columns>_key for a unique index. It must be at most 63 bytes,
+	// the longest name Postgres keeps; set it if the default is longer.
+	Name string
+	// Columns are column names or expressions, e.g., lower(email).
+	Columns []string
+	Unique  bool
+	// Method is the index method, e.g., gin. It defaults to btree.
+	Method string
+	// Where makes the index partial, e.g., deleted_at IS NULL.
+	Where string
+}
+
+type Check struct {
+	// Name must be at most 63 bytes.
+	Name string
+	// Expr is the condition rows must satisfy, e.g., quantity > 0.
+	Expr string
+}
@@ -0,0 +1,365 @@
+package milo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/pkg/errors"
+)
+
+type CreateSchemaOptions struct {
+	// IfNotExists skips tables that already exist, along with their foreign keys.
+	IfNotExists bool
+	// DropFirst drops the tables, and everything that depends on them, before creating them.
+	DropFirst bool
+}
+
+// CreateSchema creates the tables of the models of entityModelMap and of every model reachable from them through
+// relations. Tables are created in dependency order, followed by foreign key constraints, and indexes on their
+// columns, for their has-one, belongs-to and has-many relations. The constraints are deferred to the end of the
+// transaction, as Save and Delete write a model before the models it references. Then the indexes and check
+// constraints declared by the models (see Indexer and Checker) are created. Many-to-many join tables aren't created.
+func CreateSchema(ctx context.Context, db orm.DB, entityModelMap EntityModelMap, opts *CreateSchemaOptions) error {
+	if opts == nil {
+		opts = &CreateSchemaOptions{}
+	}
+
+	tables, err := schemaTables(entityModelMap)
+	if err != nil {
+		return err
+	}
+
+	indexes := make(map[*orm.Table][]*Index)
+	checks := make(map[*orm.Table][]*Check)
+
+	for _, table := range tables {
+		indexes[table], err = tableIndexes(table)
+		if err != nil {
+			return err
+		}
+
+		checks[table], err = tableChecks(table)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.DropFirst {
+		for i := len(tables) - 1; i >= 0; i-- {
+			err := db.Model(newModel(tables[i])).Context(ctx).DropTable(&orm.DropTableOptions{
+				IfExists: true,
+				Cascade:  true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "dropping table %s", tables[i].SQLName)
+			}
+		}
+	}
+
+	created := make(map[*orm.Table]bool)
+
+	for _, table := range tables {
+		if opts.IfNotExists {
+			var exists bool
+
+			_, err := db.QueryOneContext(ctx, pg.Scan(&exists), "SELECT to_regclass(?) IS NOT NULL", string(table.SQLName))
+			if err != nil {
+				return errors.Wrapf(err, "checking if table %s exists", table.SQLName)
+			}
+
+			if exists {
+				continue
+			}
+		}
+
+		err := db.Model(newModel(table)).Context(ctx).CreateTable(&orm.CreateTableOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "creating table %s", table.SQLName)
+		}
+
+		created[table] = true
+	}
+
+	for _, fk := range foreignKeys(tables) {
+		if !created[fk.table] {
+			continue
+		}
+
+		_, err := db.ExecContext(ctx, fk.sql())
+		if err != nil {
+			return errors.Wrapf(err, "creating foreign key %s", fk.String())
+		}
+
+		if fk.indexed() {
+			continue
+		}
+
+		_, err = db.ExecContext(ctx, fk.indexSQL())
+		if err != nil {
+			return errors.Wrapf(err, "creating index of foreign key %s", fk.String())
+		}
+	}
+
+	for _, table := range tables {
+		if !created[table] {
+			continue
+		}
+
+		for _, index := range indexes[table] {
+			_, err := db.ExecContext(ctx, indexSQL(table, index))
+			if err != nil {
+				return errors.Wrapf(err, "creating index %s", index.Name)
+			}
+		}
+
+		for _, check := range checks[table] {
+			_, err := db.ExecContext(ctx, checkSQL(table, check))
+			if err != nil {
+				return errors.Wrapf(err, "creating check %s", check.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func newModel(table *orm.Table) interface{} {
+	return reflect.New(table.Type).Interface()
+}
+
+// schemaTables returns the tables of the models of entityModelMap and of the models reachable from them through
+// relations, each after the tables it references.
+func schemaTables(entityModelMap EntityModelMap) ([]*orm.Table, error) {
+	var modelTypes []reflect.Type
+
+	for _, modelType := range entityModelMap {
+		if modelType.Kind() != reflect.Ptr || modelType.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("model type %s must be a pointer to a struct", modelType.String())
+		}
+
+		modelTypes = append(modelTypes, modelType.Elem())
+	}
+
+	// Sorted, so the tables are created in the same order every time.
+	sort.Slice(modelTypes, func(i, j int) bool {
+		return modelTypes[i].String() < modelTypes[j].String()
+	})
+
+	var tables []*orm.Table
+	visited := make(map[*orm.Table]bool)
+	// Models can share a table, e.g., to load fewer relations. The first one creates it.
+	names := make(map[string]bool)
+
+	var visit func(table *orm.Table)
+	visit = func(table *orm.Table) {
+		if visited[table] {
+			return
+		}
+
+		// Marked before the dependencies are visited, so relations that reference each other don't recurse forever.
+		visited[table] = true
+
+		for _, relation := range sortedRelations(table) {
+			visit(relation.JoinTable)
+		}
+
+		if !names[string(table.SQLName)] {
+			names[string(table.SQLName)] = true
+			tables = append(tables, table)
+		}
+	}
+
+	for _, modelType := range modelTypes {
+		visit(orm.GetTable(modelType))
+	}
+
+	// visit places a table after the tables it references, but tables referenced by a table's has-many and
+	// belongs-to relations reference it instead.
+	return sortTables(tables), nil
+}
+
+func sortedRelations(table *orm.Table) []*orm.Relation {
+	names := make([]string, 0, len(table.Relations))
+	for name := range table.Relations {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	relations := make([]*orm.Relation, len(names))
+	for i, name := range names {
+		relations[i] = table.Relations[name]
+	}
+
+	return relations
+}
+
+// sortTables returns tables ordered so that each table comes after the tables its foreign keys reference, keeping the
+// order of tables otherwise. Tables that reference each other keep their order.
+func sortTables(tables []*orm.Table) []*orm.Table {
+	byName := make(map[string]*orm.Table)
+	for _, table := range tables {
+		byName[string(table.SQLName)] = table
+	}
+
+	references := make(map[*orm.Table][]*orm.Table)
+	for _, fk := range foreignKeys(tables) {
+		references[fk.table] = append(references[fk.table], byName[string(fk.refTable.SQLName)])
+	}
+
+	sorted := make([]*orm.Table, 0, len(tables))
+	visited := make(map[*orm.Table]bool)
+
+	var visit func(table *orm.Table)
+	visit = func(table *orm.Table) {
+		if visited[table] {
+			return
+		}
+
+		visited[table] = true
+
+		for _, ref := range references[table] {
+			visit(ref)
+		}
+
+		sorted = append(sorted, table)
+	}
+
+	for _, table := range tables {
+		visit(table)
+	}
+
+	return sorted
+}
+
+// foreignKey is a foreign key constraint of a relation.
+type foreignKey struct {
+	table      *orm.Table
+	columns    []*orm.Field
+	refTable   *orm.Table
+	refColumns []*orm.Field
+}
+
+// foreignKeys returns the foreign keys of the relations of tables, in the order of tables. Relations of several
+// models sharing a table have one foreign key.
+func foreignKeys(tables []*orm.Table) []*foreignKey {
+	var fks []*foreignKey
+	seen := make(map[string]bool)
+
+	for _, table := range tables {
+		for _, relation := range sortedRelations(table) {
+			var fk *foreignKey
+
+			switch relation.Type {
+			case orm.HasOneRelation:
+				fk = &foreignKey{
+					table:      table,
+					columns:    relation.BaseFKs,
+					refTable:   relation.JoinTable,
+					refColumns: relation.JoinFKs,
+				}
+
+			case orm.BelongsToRelation, orm.HasManyRelation:
+				// A polymorphic relation's foreign key references more than one table.
+				if relation.Polymorphic != nil {
+					continue
+				}
+
+				fk = &foreignKey{
+					table:      relation.JoinTable,
+					columns:    relation.JoinFKs,
+					refTable:   table,
+					refColumns: relation.BaseFKs,
+				}
+
+			default:
+				continue
+			}
+
+			if seen[fk.String()] {
+				continue
+			}
+
+			seen[fk.String()] = true
+			fks = append(fks, fk)
+		}
+	}
+
+	// Foreign keys of a table referenced by another table's has-many or belongs-to relation come from that other
+	// table, so the owning table may not be in tables.
+	byName := make(map[string]*orm.Table)
+	for _, table := range tables {
+		byName[string(table.SQLName)] = table
+	}
+
+	for _, fk := range fks {
+		if table, ok := byName[string(fk.table.SQLName)]; ok {
+			fk.table = table
+		}
+	}
+
+	return fks
+}
+
+func (fk *foreignKey) String() string {
+	return fmt.Sprintf("%s(%s) -> %s(%s)", fk.table.SQLName, joinColumns(fk.columns), fk.refTable.SQLName, joinColumns(fk.refColumns))
+}
+
+func (fk *foreignKey) sql() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "ALTER TABLE %s ADD FOREIGN KEY (%s) REFERENCES %s (%s)", fk.table.SQLName, joinColumns(fk.columns),
+		fk.refTable.SQLName, joinColumns(fk.refColumns))
+
+	for _, column := range fk.columns {
+		if column.OnDelete != "" {
+			fmt.Fprintf(&b, " ON DELETE %s", column.OnDelete)
+			break
+		}
+	}
+
+	for _, column := range fk.columns {
+		if column.OnUpdate != "" {
+			fmt.Fprintf(&b, " ON UPDATE %s", column.OnUpdate)
+			break
+		}
+	}
+
+	b.WriteString(" DEFERRABLE INITIALLY DEFERRED")
+
+	return b.String()
+}
+
+// indexed returns true if the primary key of the foreign key's table starts with the foreign key's columns, so the
+// foreign key doesn't need an index of its own.
+func (fk *foreignKey) indexed() bool {
+	pks := make([]string, len(fk.table.PKs))
+	for i, pk := range fk.table.PKs {
+		pks[i] = pk.SQLName
+	}
+
+	columns := make([]string, len(fk.columns))
+	for i, column := range fk.columns {
+		columns[i] = column.SQLName
+	}
+
+	return hasIndex([][]string{pks}, columns)
+}
+
+func (fk *foreignKey) indexSQL() string {
+	return fmt.Sprintf("CREATE INDEX ON %s (%s)", fk.table.SQLName, joinColumns(fk.columns))
+}
+
+func joinColumns(fields []*orm.Field) string {
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = string(field.Column)
+	}
+
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,482 @@
+package milo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/pkg/errors"
+)
+
+type SchemaDriftKind string
+
+const (
+	MissingTable           SchemaDriftKind = "missing table"
+	MissingColumn          SchemaDriftKind = "missing column"
+	TypeMismatch           SchemaDriftKind = "type mismatch"
+	NullabilityMismatch    SchemaDriftKind = "nullability mismatch"
+	MissingForeignKeyIndex SchemaDriftKind = "missing foreign key index"
+	MissingIndex           SchemaDriftKind = "missing index"
+	MissingCheck           SchemaDriftKind = "missing check"
+)
+
+// SchemaDrift is a difference between a model and the database.
+type SchemaDrift struct {
+	Kind  SchemaDriftKind
+	Table string
+	// Column is the column of a missing column or of a mismatch, or the comma separated columns of a foreign key
+	// without an index.
+	Column string
+	// Name is the name of a missing index or check.
+	Name string
+
+	// Expected and Actual are the types or nullabilities of a mismatch.
+	Expected string
+	Actual   string
+}
+
+func (d *SchemaDrift) String() string {
+	switch d.Kind {
+	case MissingTable:
+		return fmt.Sprintf("%s: %s", d.Table, d.Kind)
+	case TypeMismatch, NullabilityMismatch:
+		return fmt.Sprintf("%s.%s: %s (model: %s, database: %s)", d.Table, d.Column, d.Kind, d.Expected, d.Actual)
+	case MissingForeignKeyIndex:
+		return fmt.Sprintf("%s(%s): %s", d.Table, d.Column, d.Kind)
+	case MissingIndex, MissingCheck:
+		return fmt.Sprintf("%s: %s %s", d.Table, d.Kind, d.Name)
+	default:
+		return fmt.Sprintf("%s.%s: %s", d.Table, d.Column, d.Kind)
+	}
+}
+
+// SchemaDriftError is returned by CheckSchema when models and the database differ.
+type SchemaDriftError struct {
+	Drifts []*SchemaDrift
+}
+
+func (e *SchemaDriftError) Error() string {
+	lines := make([]string, len(e.Drifts))
+	for i, drift := range e.Drifts {
+		lines[i] = drift.String()
+	}
+
+	return fmt.Sprintf("schema drift: %s", strings.Join(lines, "; "))
+}
+
+// CheckSchema returns a *SchemaDriftError if DetectSchemaDrift finds differences, e.g., to refuse to start an
+// application that wasn't migrated.
+func CheckSchema(ctx context.Context, db orm.DB, entityModelMap EntityModelMap) error {
+	drifts, err := DetectSchemaDrift(ctx, db, entityModelMap)
+	if err != nil {
+		return err
+	}
+
+	if len(drifts) > 0 {
+		return &SchemaDriftError{
+			Drifts: drifts,
+		}
+	}
+
+	return nil
+}
+
+type dbColumn struct {
+	TableSchema            string
+	TableName              string
+	ColumnName             string
+	DataType               string
+	UdtName                string
+	CharacterMaximumLength int
+	IsNullable             string
+	ColumnDefault          string
+}
+
+type dbIndex struct {
+	TableSchema string
+	TableName   string
+	Name        string
+	Columns     []string `pg:",array"`
+}
+
+type dbCheck struct {
+	TableSchema string
+	TableName   string
+	Name        string
+}
+
+// DetectSchemaDrift compares the tables of the models of entityModelMap, and of the models reachable from them
+// through relations, with information_schema. It reports missing tables and columns, columns whose type or nullability
+// differs from the model's, foreign keys (see CreateSchema) without an index starting with their columns and missing
+// declared indexes and checks (see Indexer and Checker). Declared indexes and checks are matched by name.
+//
+// A column is expected to be NOT NULL if its field is a primary key or has the notnull tag. A NOT NULL column without
+// a default is also reported for a field without the notnull tag, as go-pg inserts NULL for the field's zero value,
+// unless the field has the use_zero tag and can't be nil, as go-pg then inserts the zero value itself.
+func DetectSchemaDrift(ctx context.Context, db orm.DB, entityModelMap EntityModelMap) ([]*SchemaDrift, error) {
+	tables, err := schemaTables(entityModelMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var currentSchema string
+
+	_, err = db.QueryOneContext(ctx, orm.Scan(&currentSchema), "SELECT current_schema()")
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting current schema")
+	}
+
+	var columns []*dbColumn
+
+	_, err = db.QueryContext(ctx, &columns, `
+		SELECT table_schema, table_name, column_name, data_type, udt_name, character_maximum_length, is_nullable, column_default
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting columns")
+	}
+
+	var indexes []*dbIndex
+
+	// Expressions of an index have no attribute, so their column is NULL and doesn't match a foreign key's column.
+	_, err = db.QueryContext(ctx, &indexes, `
+		SELECT n.nspname AS table_schema, t.relname AS table_name, c.relname AS name,
+			array_agg(a.attname ORDER BY k.ord) AS columns
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		GROUP BY i.indexrelid, n.nspname, t.relname, c.relname
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting indexes")
+	}
+
+	var checks []*dbCheck
+
+	_, err = db.QueryContext(ctx, &checks, `
+		SELECT n.nspname AS table_schema, t.relname AS table_name, c.conname AS name
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE c.contype = 'c'
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting checks")
+	}
+
+	columnsByTable := make(map[string]map[string]*dbColumn)
+	for _, column := range columns {
+		name := column.TableSchema + "." + column.TableName
+
+		if columnsByTable[name] == nil {
+			columnsByTable[name] = make(map[string]*dbColumn)
+		}
+
+		columnsByTable[name][column.ColumnName] = column
+	}
+
+	indexesByTable := make(map[string][][]string)
+	// Index and check names are qualified by the table's name.
+	names := make(map[string]bool)
+
+	for _, index := range indexes {
+		name := index.TableSchema + "." + index.TableName
+		indexesByTable[name] = append(indexesByTable[name], index.Columns)
+		names[name+"."+index.Name] = true
+	}
+
+	for _, check := range checks {
+		names[check.TableSchema+"."+check.TableName+"."+check.Name] = true
+	}
+
+	var drifts []*SchemaDrift
+
+	for _, table := range tables {
+		tableName := qualifiedTableName(table.SQLName, currentSchema)
+
+		dbColumns, ok := columnsByTable[tableName]
+		if !ok {
+			drifts = append(drifts, &SchemaDrift{
+				Kind:  MissingTable,
+				Table: tableName,
+			})
+
+			continue
+		}
+
+		for _, field := range table.Fields {
+			column, ok := dbColumns[field.SQLName]
+			if !ok {
+				drifts = append(drifts, &SchemaDrift{
+					Kind:   MissingColumn,
+					Table:  tableName,
+					Column: field.SQLName,
+				})
+
+				continue
+			}
+
+			if expected, actual := canonicalSQLType(fieldSQLType(field)), canonicalColumnType(column); expected != actual {
+				drifts = append(drifts, &SchemaDrift{
+					Kind:     TypeMismatch,
+					Table:    tableName,
+					Column:   field.SQLName,
+					Expected: fieldSQLType(field),
+					Actual:   columnType(column),
+				})
+			}
+
+			notNull := isNotNull(table, field)
+			dbNotNull := column.IsNullable == "NO"
+
+			if notNull != dbNotNull && (notNull || (column.ColumnDefault == "" && writesNull(field))) {
+				drifts = append(drifts, &SchemaDrift{
+					Kind:     NullabilityMismatch,
+					Table:    tableName,
+					Column:   field.SQLName,
+					Expected: nullability(notNull),
+					Actual:   nullability(dbNotNull),
+				})
+			}
+		}
+
+		indexes, err := tableIndexes(table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, index := range indexes {
+			if !names[tableName+"."+index.Name] {
+				drifts = append(drifts, &SchemaDrift{
+					Kind:  MissingIndex,
+					Table: tableName,
+					Name:  index.Name,
+				})
+			}
+		}
+
+		checks, err := tableChecks(table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, check := range checks {
+			if !names[tableName+"."+check.Name] {
+				drifts = append(drifts, &SchemaDrift{
+					Kind:  MissingCheck,
+					Table: tableName,
+					Name:  check.Name,
+				})
+			}
+		}
+	}
+
+	for _, fk := range foreignKeys(tables) {
+		tableName := qualifiedTableName(fk.table.SQLName, currentSchema)
+
+		// Missing tables are already reported.
+		if _, ok := columnsByTable[tableName]; !ok {
+			continue
+		}
+
+		columns := make([]string, len(fk.columns))
+		for i, field := range fk.columns {
+			columns[i] = field.SQLName
+		}
+
+		if !hasIndex(indexesByTable[tableName], columns) {
+			drifts = append(drifts, &SchemaDrift{
+				Kind:   MissingForeignKeyIndex,
+				Table:  tableName,
+				Column: strings.Join(columns, ", "),
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+// qualifiedTableName returns the schema qualified, unquoted name of a table.
+func qualifiedTableName(sqlName types.Safe, currentSchema string) string {
+	parts := strings.Split(string(sqlName), `"."`)
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(strings.Trim(part, `"`), `""`, `"`)
+	}
+
+	if len(parts) == 1 {
+		return currentSchema + "." + parts[0]
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// hasIndex returns true if one of indexes starts with columns, in any order.
+func hasIndex(indexes [][]string, columns []string) bool {
+	for _, index := range indexes {
+		if len(index) < len(columns) {
+			continue
+		}
+
+		leading := make(map[string]bool)
+		for _, column := range index[:len(columns)] {
+			leading[column] = true
+		}
+
+		found := true
+		for _, column := range columns {
+			if !leading[column] {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isNotNull(table *orm.Table, field *orm.Field) bool {
+	for _, pk := range table.PKs {
+		if pk == field {
+			return true
+		}
+	}
+
+	for _, option := range strings.Split(field.Field.Tag.Get("pg"), ",")[1:] {
+		if option == "notnull" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writesNull returns true if go-pg can write NULL to the column of field: for the zero value of a field without the
+// use_zero tag, or for a nil value.
+func writesNull(field *orm.Field) bool {
+	if field.NullZero() {
+		return true
+	}
+
+	switch field.Type.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	default:
+		return false
+	}
+}
+
+func nullability(notNull bool) string {
+	if notNull {
+		return "NOT NULL"
+	}
+
+	return "NULL"
+}
+
+// fieldSQLType returns the type go-pg creates the column of field with.
+func fieldSQLType(field *orm.Field) string {
+	if field.UserSQLType != "" {
+		return field.UserSQLType
+	}
+
+	return field.SQLType
+}
+
+// columnType returns the type of column as it would be written in CREATE TABLE.
+func columnType(column *dbColumn) string {
+	switch column.DataType {
+	case "ARRAY":
+		return strings.TrimPrefix(column.UdtName, "_") + "[]"
+	case "USER-DEFINED":
+		return column.UdtName
+	}
+
+	if column.CharacterMaximumLength > 0 {
+		return fmt.Sprintf("%s(%d)", column.DataType, column.CharacterMaximumLength)
+	}
+
+	return column.DataType
+}
+
+// sqlTypeAliases maps names of types to the names information_schema uses for them (udt_name).
+var sqlTypeAliases = map[string]string{
+	"int":                         "int4",
+	"integer":                     "int4",
+	"serial":                      "int4",
+	"bigint":                      "int8",
+	"bigserial":                   "int8",
+	"smallint":                    "int2",
+	"smallserial":                 "int2",
+	"boolean":                     "bool",
+	"double precision":            "float8",
+	"real":                        "float4",
+	"character varying":           "varchar",
+	"character":                   "bpchar",
+	"char":                        "bpchar",
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+	"time with time zone":         "timetz",
+	"time without time zone":      "time",
+	"decimal":                     "numeric",
+}
+
+var sqlTypeModifierRegexp = regexp.MustCompile(`\s*\(([^)]*)\)`)
+
+// canonicalSQLType returns a name of typ that can be compared with canonicalColumnType. The length of character types
+// is kept; other type modifiers, e.g., the precision of numeric, are ignored.
+func canonicalSQLType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+
+	array := false
+	if strings.HasSuffix(typ, "[]") {
+		array = true
+		typ = strings.TrimSuffix(typ, "[]")
+	}
+
+	var length string
+	if m := sqlTypeModifierRegexp.FindStringSubmatch(typ); m != nil {
+		length = m[1]
+		typ = sqlTypeModifierRegexp.ReplaceAllString(typ, "")
+	}
+
+	typ = strings.Join(strings.Fields(typ), " ")
+
+	if alias, ok := sqlTypeAliases[typ]; ok {
+		typ = alias
+	}
+
+	// char is char(1).
+	if typ == "bpchar" && length == "" {
+		length = "1"
+	}
+
+	if (typ == "varchar" || typ == "bpchar") && length != "" && !array {
+		typ += "(" + length + ")"
+	}
+
+	if array {
+		typ = "_" + typ
+	}
+
+	return typ
+}
+
+func canonicalColumnType(column *dbColumn) string {
+	if column.CharacterMaximumLength > 0 {
+		return fmt.Sprintf("%s(%d)", column.UdtName, column.CharacterMaximumLength)
+	}
+
+	return column.UdtName
+}
@@ -0,0 +1,133 @@
+package milo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalSQLType(t *testing.T) {
+	tests := []struct {
+		typ      string
+		column   dbColumn
+		expected bool
+	}{
+		{typ: "text", column: dbColumn{DataType: "text", UdtName: "text"}, expected: true},
+		{typ: "bigint", column: dbColumn{DataType: "bigint", UdtName: "int8"}, expected: true},
+		{typ: "bigserial", column: dbColumn{DataType: "bigint", UdtName: "int8"}, expected: true},
+		{typ: "timestamptz", column: dbColumn{DataType: "timestamp with time zone", UdtName: "timestamptz"}, expected: true},
+		{typ: "timestamptz", column: dbColumn{DataType: "timestamp without time zone", UdtName: "timestamp"}, expected: false},
+		{typ: "double precision", column: dbColumn{DataType: "double precision", UdtName: "float8"}, expected: true},
+		{typ: "varchar(255)", column: dbColumn{DataType: "character varying", UdtName: "varchar", CharacterMaximumLength: 255}, expected: true},
+		{typ: "varchar(100)", column: dbColumn{DataType: "character varying", UdtName: "varchar", CharacterMaximumLength: 255}, expected: false},
+		{typ: "char", column: dbColumn{DataType: "character", UdtName: "bpchar", CharacterMaximumLength: 1}, expected: true},
+		{typ: "numeric(10, 2)", column: dbColumn{DataType: "numeric", UdtName: "numeric"}, expected: true},
+		{typ: "text[]", column: dbColumn{DataType: "ARRAY", UdtName: "_text"}, expected: true},
+		{typ: "integer[]", column: dbColumn{DataType: "ARRAY", UdtName: "_int4"}, expected: true},
+		{typ: "mood", column: dbColumn{DataType: "USER-DEFINED", UdtName: "mood"}, expected: true},
+		{typ: "jsonb", column: dbColumn{DataType: "json", UdtName: "json"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.column.UdtName, func(t *testing.T) {
+			column := tt.column
+			assert.Equal(t, tt.expected, canonicalSQLType(tt.typ) == canonicalColumnType(&column))
+		})
+	}
+}
+
+func TestQualifiedTableName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("public.users", qualifiedTableName(types.Safe(`"users"`), "public"))
+	assert.Equal("auth.users", qualifiedTableName(types.Safe(`"auth"."users"`), "public"))
+}
+
+func TestHasIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	indexes := [][]string{{"id"}, {"tenant_id", "user_id", "created_at"}}
+
+	assert.True(hasIndex(indexes, []string{"id"}))
+	assert.True(hasIndex(indexes, []string{"user_id", "tenant_id"}))
+	assert.False(hasIndex(indexes, []string{"user_id"}))
+	assert.False(hasIndex(indexes, []string{"created_at"}))
+}
+
+func TestWritesNull(t *testing.T) {
+	assert := assert.New(t)
+
+	table := orm.GetTable(reflect.TypeOf(accountModel{}))
+
+	// A use_zero field is written as its zero value, so a NOT NULL column without a default isn't drift.
+	assert.False(writesNull(table.FieldsMap["quantity"]))
+	assert.True(writesNull(table.FieldsMap["email"]))
+	assert.True(writesNull(table.FieldsMap["tags"]))
+	assert.True(writesNull(table.FieldsMap["deleted_at"]))
+}
+
+func TestSchemaDriftError(t *testing.T) {
+	err := &SchemaDriftError{
+		Drifts: []*SchemaDrift{
+			{Kind: MissingTable, Table: "public.profiles"},
+			{Kind: MissingColumn, Table: "public.users", Column: "name_last"},
+			{Kind: TypeMismatch, Table: "public.users", Column: "id", Expected: "text", Actual: "uuid"},
+			{Kind: MissingForeignKeyIndex, Table: "public.addresses", Column: "user_id"},
+		},
+	}
+
+	assert.EqualError(t, err, "schema drift: public.profiles: missing table; public.users.name_last: missing column; "+
+		"public.users.id: type mismatch (model: text, database: uuid); public.addresses(user_id): missing foreign key index")
+}
+
+func TestStore_DetectSchemaDrift(t *testing.T) {
+	assert := assert.New(t)
+
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	defer db.Close()
+
+	ctx := context.Background()
+
+	entityModelMap := EntityModelMap{
+		reflect.TypeOf(&userEntityPtr{}): reflect.TypeOf(&userModelPtr{}),
+	}
+
+	err := CreateSchema(ctx, db, entityModelMap, &CreateSchemaOptions{DropFirst: true})
+	assert.NoError(err)
+
+	err = CheckSchema(ctx, db, entityModelMap)
+	assert.NoError(err)
+
+	_, err = db.Exec(`
+		DROP TABLE locations;
+		ALTER TABLE users DROP COLUMN name_last;
+		ALTER TABLE users ALTER COLUMN name_first TYPE varchar(50);
+		ALTER TABLE profiles ALTER COLUMN about SET NOT NULL;
+		DROP INDEX addresses_user_id_idx;
+	`)
+	assert.NoError(err)
+
+	drifts, err := DetectSchemaDrift(ctx, db, entityModelMap)
+	assert.NoError(err)
+	assert.Equal([]*SchemaDrift{
+		{Kind: NullabilityMismatch, Table: "public.profiles", Column: "about", Expected: "NULL", Actual: "NOT NULL"},
+		{Kind: TypeMismatch, Table: "public.users", Column: "name_first", Expected: "text", Actual: "character varying(50)"},
+		{Kind: MissingColumn, Table: "public.users", Column: "name_last"},
+		{Kind: MissingTable, Table: "public.locations"},
+		{Kind: MissingForeignKeyIndex, Table: "public.addresses", Column: "user_id"},
+	}, drifts)
+
+	err = CheckSchema(ctx, db, entityModelMap)
+	assert.IsType(&SchemaDriftError{}, err)
+}
@@ -0,0 +1,134 @@
+package milo
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+)
+
+var indexNameRegexp = regexp.MustCompile(`\W+`)
+
+// maxIdentifierLength is the length in bytes of the longest identifier Postgres keeps (NAMEDATALEN - 1). Longer
+// names are truncated, so they wouldn't match the names of the model.
+const maxIdentifierLength = 63
+
+// tableIndexes returns the indexes declared by the model of table, with their default names.
+func tableIndexes(table *orm.Table) ([]*Index, error) {
+	indexer, ok := reflect.New(table.Type).Interface().(Indexer)
+	if !ok {
+		return nil, nil
+	}
+
+	tableName := qualifiedTableName(table.SQLName, "")
+	tableName = tableName[strings.LastIndex(tableName, ".")+1:]
+
+	var indexes []*Index
+
+	for _, index := range indexer.Indexes() {
+		if len(index.Columns) == 0 {
+			return nil, fmt.Errorf("index %q of table %s has no columns", index.Name, table.SQLName)
+		}
+
+		if index.Name == "" {
+			parts := make([]string, len(index.Columns))
+			for i, column := range index.Columns {
+				parts[i] = strings.Trim(indexNameRegexp.ReplaceAllString(column, "_"), "_")
+			}
+
+			suffix := "idx"
+			if index.Unique {
+				suffix = "key"
+			}
+
+			named := *index
+			named.Name = fmt.Sprintf("%s_%s_%s", tableName, strings.Join(parts, "_"), suffix)
+			index = &named
+		}
+
+		if len(index.Name) > maxIdentifierLength {
+			return nil, fmt.Errorf("name %q of index of table %s is longer than %d bytes", index.Name, table.SQLName, maxIdentifierLength)
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	return indexes, nil
+}
+
+// tableChecks returns the check constraints declared by the model of table.
+func tableChecks(table *orm.Table) ([]*Check, error) {
+	checker, ok := reflect.New(table.Type).Interface().(Checker)
+	if !ok {
+		return nil, nil
+	}
+
+	checks := checker.Checks()
+
+	for _, check := range checks {
+		if check.Name == "" {
+			return nil, fmt.Errorf("check %q of table %s has no name", check.Expr, table.SQLName)
+		}
+
+		if len(check.Name) > maxIdentifierLength {
+			return nil, fmt.Errorf("name %q of check of table %s is longer than %d bytes", check.Name, table.SQLName, maxIdentifierLength)
+		}
+	}
+
+	return checks, nil
+}
+
+func indexSQL(table *orm.Table, index *Index) string {
+	var b strings.Builder
+
+	b.WriteString("CREATE ")
+
+	if index.Unique {
+		b.WriteString("UNIQUE ")
+	}
+
+	fmt.Fprintf(&b, "INDEX %s ON %s", types.AppendIdent(nil, index.Name, 1), table.SQLName)
+
+	if index.Method != "" {
+		fmt.Fprintf(&b, " USING %s", index.Method)
+	}
+
+	fmt.Fprintf(&b, " (%s)", strings.Join(index.Columns, ", "))
+
+	if index.Where != "" {
+		fmt.Fprintf(&b, " WHERE %s", index.Where)
+	}
+
+	return b.String()
+}
+
+func checkSQL(table *orm.Table, check *Check) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s)", table.SQLName, types.AppendIdent(nil, check.Name, 1), check.Expr)
+}
+
+// declaredIndex returns the index named name declared by one of the models of entityModelMap, or of the models
+// reachable from them, or nil if there isn't one.
+func declaredIndex(entityModelMap EntityModelMap, name string) *Index {
+	tables, err := schemaTables(entityModelMap)
+	if err != nil {
+		return nil
+	}
+
+	for _, table := range tables {
+		indexes, err := tableIndexes(table)
+		if err != nil {
+			continue
+		}
+
+		for _, index := range indexes {
+			if index.Name == name {
+				return index
+			}
+		}
+	}
+
+	return nil
+}
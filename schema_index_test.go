@@ -0,0 +1,193 @@
+package milo
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type accountEntity struct {
+	ID       string
+	Email    string
+	Tags     []string
+	Quantity int
+}
+
+type accountModel struct {
+	tableName struct{} `pg:"accounts"`
+
+	ID        string     `pg:"id"`
+	Email     string     `pg:"email"`
+	Tags      []string   `pg:"tags,array"`
+	Quantity  int        `pg:"quantity,use_zero"`
+	DeletedAt *time.Time `pg:"deleted_at"`
+}
+
+var _ Indexer = (*accountModel)(nil)
+var _ Checker = (*accountModel)(nil)
+
+func (a *accountModel) FromEntity(e interface{}) error {
+	entity := e.(*accountEntity)
+
+	a.ID = entity.ID
+	a.Email = entity.Email
+	a.Tags = entity.Tags
+	a.Quantity = entity.Quantity
+
+	return nil
+}
+
+func (a *accountModel) ToEntity() (interface{}, error) {
+	return &accountEntity{
+		ID:       a.ID,
+		Email:    a.Email,
+		Tags:     a.Tags,
+		Quantity: a.Quantity,
+	}, nil
+}
+
+func (a *accountModel) Indexes() []*Index {
+	return []*Index{
+		{Columns: []string{"lower(email)"}, Unique: true, Where: "deleted_at IS NULL"},
+		{Columns: []string{"tags"}, Method: "gin"},
+		{Name: "accounts_email_quantity", Columns: []string{"email", "quantity"}},
+	}
+}
+
+func (a *accountModel) Checks() []*Check {
+	return []*Check{
+		{Name: "accounts_quantity_check", Expr: "quantity >= 0"},
+	}
+}
+
+type longIndexModel struct {
+	tableName struct{} `pg:"long_indexes"`
+
+	ID string `pg:"id"`
+}
+
+func (l *longIndexModel) FromEntity(e interface{}) error {
+	return nil
+}
+
+func (l *longIndexModel) ToEntity() (interface{}, error) {
+	return nil, nil
+}
+
+func (l *longIndexModel) Indexes() []*Index {
+	return []*Index{
+		{Columns: []string{"organization_id", "external_reference_number", "created_at"}, Unique: true},
+	}
+}
+
+func (l *longIndexModel) Checks() []*Check {
+	return []*Check{
+		{Name: strings.Repeat("c", 64), Expr: "id <> ''"},
+	}
+}
+
+func TestTableIndexes(t *testing.T) {
+	assert := assert.New(t)
+
+	table := orm.GetTable(reflect.TypeOf(accountModel{}))
+
+	indexes, err := tableIndexes(table)
+	assert.NoError(err)
+
+	var sql []string
+	for _, index := range indexes {
+		sql = append(sql, indexSQL(table, index))
+	}
+
+	assert.Equal([]string{
+		`CREATE UNIQUE INDEX "accounts_lower_email_key" ON "accounts" (lower(email)) WHERE deleted_at IS NULL`,
+		`CREATE INDEX "accounts_tags_idx" ON "accounts" USING gin (tags)`,
+		`CREATE INDEX "accounts_email_quantity" ON "accounts" (email, quantity)`,
+	}, sql)
+
+	checks, err := tableChecks(table)
+	assert.NoError(err)
+	assert.Len(checks, 1)
+	assert.Equal(`ALTER TABLE "accounts" ADD CONSTRAINT "accounts_quantity_check" CHECK (quantity >= 0)`, checkSQL(table, checks[0]))
+
+	// Postgres would truncate names longer than 63 bytes.
+	table = orm.GetTable(reflect.TypeOf(longIndexModel{}))
+
+	_, err = tableIndexes(table)
+	assert.EqualError(err, `name "long_indexes_organization_id_external_reference_number_created_at_key" of index of table "long_indexes" is longer than 63 bytes`)
+
+	_, err = tableChecks(table)
+	assert.EqualError(err, `name "`+strings.Repeat("c", 64)+`" of check of table "long_indexes" is longer than 63 bytes`)
+
+	// Models without declarations have none.
+	indexes, err = tableIndexes(orm.GetTable(reflect.TypeOf(userModelPtr{})))
+	assert.NoError(err)
+	assert.Empty(indexes)
+}
+
+func TestStore_Indexes(t *testing.T) {
+	assert := assert.New(t)
+
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	defer db.Close()
+
+	ctx := context.Background()
+
+	entityModelMap := EntityModelMap{
+		reflect.TypeOf(&accountEntity{}): reflect.TypeOf(&accountModel{}),
+	}
+
+	err := CreateSchema(ctx, db, entityModelMap, &CreateSchemaOptions{DropFirst: true})
+	assert.NoError(err)
+
+	err = CheckSchema(ctx, db, entityModelMap)
+	assert.NoError(err)
+
+	store, err := NewStore(db, entityModelMap)
+	assert.NoError(err)
+
+	err = store.Save(ctx, &accountEntity{ID: uuid.New().String(), Email: "jane@example.com"})
+	assert.NoError(err)
+
+	err = store.Save(ctx, &accountEntity{ID: uuid.New().String(), Email: "JANE@example.com"})
+
+	assert.ErrorIs(err, ErrUniqueViolation)
+
+	var dbErr *DBError
+	if assert.ErrorAs(err, &dbErr) {
+		assert.Equal("accounts", dbErr.Table)
+		assert.Equal("accounts_lower_email_key", dbErr.Constraint)
+		assert.Equal([]string{"lower(email)"}, dbErr.Index.Columns)
+	}
+
+	err = store.Save(ctx, &accountEntity{ID: uuid.New().String(), Email: "john@example.com", Quantity: -1})
+	assert.ErrorIs(err, ErrCheckViolation)
+
+	// quantity is use_zero, so go-pg never writes NULL to it and NOT NULL isn't drift.
+	_, err = db.Exec(`
+		DROP INDEX accounts_tags_idx;
+		ALTER TABLE accounts DROP CONSTRAINT accounts_quantity_check;
+		ALTER TABLE accounts ALTER COLUMN quantity SET NOT NULL;
+	`)
+	assert.NoError(err)
+
+	drifts, err := DetectSchemaDrift(ctx, db, entityModelMap)
+	assert.NoError(err)
+	assert.Equal([]*SchemaDrift{
+		{Kind: MissingIndex, Table: "public.accounts", Name: "accounts_tags_idx"},
+		{Kind: MissingCheck, Table: "public.accounts", Name: "accounts_quantity_check"},
+	}, drifts)
+}
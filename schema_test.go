@@ -0,0 +1,126 @@
+package milo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type categoryModel struct {
+	tableName struct{} `pg:"categories"`
+
+	ID       string `pg:"id"`
+	ParentID string `pg:"parent_id,on_delete:CASCADE"`
+
+	Parent *categoryModel `pg:"rel:has-one"`
+}
+
+func (c *categoryModel) FromEntity(e interface{}) error {
+	return nil
+}
+
+func (c *categoryModel) ToEntity() (interface{}, error) {
+	return nil, nil
+}
+
+func TestSchemaTables(t *testing.T) {
+	assert := assert.New(t)
+
+	tables, err := schemaTables(EntityModelMap{
+		reflect.TypeOf(&userEntityPtr{}): reflect.TypeOf(&userModelPtr{}),
+		reflect.TypeOf(&userEntity{}):    reflect.TypeOf(&userModel{}),
+		reflect.TypeOf(&hookEntity{}):    reflect.TypeOf(&hookModel{}),
+	})
+	assert.NoError(err)
+
+	var names []string
+	for _, table := range tables {
+		names = append(names, string(table.SQLName))
+	}
+
+	// users references profiles, and addresses and locations reference users.
+	assert.Equal([]string{`"hook_models"`, `"profiles"`, `"users"`, `"addresses"`, `"locations"`}, names)
+
+	var sql []string
+	for _, fk := range foreignKeys(tables) {
+		sql = append(sql, fk.sql())
+	}
+
+	assert.Equal([]string{
+		`ALTER TABLE "addresses" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") DEFERRABLE INITIALLY DEFERRED`,
+		`ALTER TABLE "locations" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") DEFERRABLE INITIALLY DEFERRED`,
+		`ALTER TABLE "users" ADD FOREIGN KEY ("profile_id") REFERENCES "profiles" ("id") DEFERRABLE INITIALLY DEFERRED`,
+	}, sql)
+
+	_, err = schemaTables(EntityModelMap{
+		reflect.TypeOf(&userEntity{}): reflect.TypeOf(userModel{}),
+	})
+	assert.EqualError(err, "model type milo.userModel must be a pointer to a struct")
+}
+
+func TestSchemaTables_SelfReference(t *testing.T) {
+	assert := assert.New(t)
+
+	tables, err := schemaTables(EntityModelMap{
+		reflect.TypeOf(&categoryModel{}): reflect.TypeOf(&categoryModel{}),
+	})
+	assert.NoError(err)
+	assert.Len(tables, 1)
+
+	fks := foreignKeys(tables)
+	assert.Len(fks, 1)
+	assert.Equal(`ALTER TABLE "categories" ADD FOREIGN KEY ("parent_id") REFERENCES "categories" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`, fks[0].sql())
+}
+
+func TestStore_CreateSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	// See docker-compose.yml
+	db := pg.Connect(&pg.Options{
+		Addr:     "localhost:8200",
+		User:     "postgres",
+		Password: "password",
+		Database: "milo",
+	})
+	defer db.Close()
+
+	ctx := context.Background()
+
+	entityModelMap := EntityModelMap{
+		reflect.TypeOf(&userEntityPtr{}): reflect.TypeOf(&userModelPtr{}),
+	}
+
+	err := CreateSchema(ctx, db, entityModelMap, &CreateSchemaOptions{DropFirst: true})
+	assert.NoError(err)
+
+	err = CreateSchema(ctx, db, entityModelMap, nil)
+	assert.Error(err, "tables already exist")
+
+	err = CreateSchema(ctx, db, entityModelMap, &CreateSchemaOptions{IfNotExists: true})
+	assert.NoError(err)
+
+	store, err := NewStore(db, entityModelMap)
+	assert.NoError(err)
+
+	// Save inserts the user before the profile it references.
+	user := &userEntityPtr{
+		ID:      uuid.New().String(),
+		Profile: &profileEntity{ID: uuid.New().String()},
+		Addresses: []*addressEntity{
+			{ID: uuid.New().String()},
+		},
+	}
+
+	err = store.Save(ctx, user)
+	assert.NoError(err)
+
+	_, err = db.Exec("INSERT INTO addresses (id, user_id) VALUES (?, ?)", uuid.New().String(), uuid.New().String())
+	assert.Error(err, "addresses.user_id references users")
+
+	err = store.Delete(ctx, user)
+	assert.NoError(err)
+}
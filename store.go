@@ -33,6 +33,11 @@ type Storer interface {
 type Store struct {
 	db             orm.DB
 	entityModelMap EntityModelMap
+
+	// savepoints is true if Transaction runs fn in a savepoint of the store's transaction (see NewSavepointStore).
+	savepoints bool
+	// savepointDepth is the number of savepoints the store is in.
+	savepointDepth int
 }
 
 var _ Storer = (*Store)(nil)
@@ -60,56 +65,110 @@ func NewStore(db orm.DB, entityModelMap EntityModelMap) (*Store, error) {
 	}, nil
 }
 
+// NewSavepointStore returns a store for tx whose Transaction method runs fn in a savepoint of tx instead of
+// returning an error. If fn returns an error, the writes of fn are rolled back to the savepoint; tx itself is never
+// committed or rolled back by the store. Save and Delete outside of Transaction run in a savepoint too, so a failed
+// write, e.g., a unique violation, doesn't abort tx.
+func NewSavepointStore(tx *pg.Tx, entityModelMap EntityModelMap) (*Store, error) {
+	s, err := NewStore(tx, entityModelMap)
+	if err != nil {
+		return nil, err
+	}
+
+	s.savepoints = true
+
+	return s, nil
+}
+
 func (s *Store) inTransaction() bool {
 	_, ok := s.db.(*pg.Tx)
 	return ok
 }
 
+// applyExpressionsToQuery adds exprs to query after checking that every column they reference is a column of the
+// query's model. Conditions are added as a single condition (top level conditions are joined with AND) and orders are
+// added in the order they appear.
 func applyExpressionsToQuery(exprs []Expression, query *orm.Query) error {
-	for _, e := range exprs {
-		if len(e.exprs) > 0 {
+	return applyExpressionsToQueryRecording(exprs, query, nil)
+}
 
-			query.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
-				err := applyExpressionsToQuery(e.exprs, q)
-				return q, err
-			})
+// applyExpressionsToQueryRecording is applyExpressionsToQuery, but the params of exprs are passed through r (see
+// Store.FindSQL).
+func applyExpressionsToQueryRecording(exprs []Expression, query *orm.Query, r *paramRecorder) error {
+	table := query.TableModel().Table()
 
-		} else {
+	err := validateExpressions(table, exprs)
+	if err != nil {
+		return err
+	}
 
-			var condition string
-			var params []interface{}
+	alias := string(table.Alias)
 
-			if e.op == OpIsNull || e.op == OpIsNotNull {
-				condition = fmt.Sprintf("%s.%s %s", query.TableModel().Table().Alias, e.column, e.op)
-			} else {
-				condition = fmt.Sprintf("%s.%s %s ?", query.TableModel().Table().Alias, e.column, e.op)
-				params = append(params, e.Value())
-			}
+	var conditions []Expression
 
-			switch e.t {
-			case expressionTypeAnd:
-				query.Where(condition, params...)
+	for _, e := range exprs {
+		if e.t != expressionTypeOrder {
+			conditions = append(conditions, e)
+			continue
+		}
+
+		order, params, err := buildOrder(alias, e)
+		if err != nil {
+			return err
+		}
 
-			case expressionTypeOr:
-				query.WhereOr(condition, params...)
+		query.OrderExpr(order, r.wrap(params)...)
+	}
 
-			default:
-				return fmt.Errorf("unknown expressionType: %s", reflect.TypeOf(e.t).String())
-			}
+	if len(conditions) == 0 {
+		return nil
+	}
 
-		}
+	condition, params, err := buildCondition(alias, And(conditions...))
+	if err != nil {
+		return err
 	}
 
+	query.Where(condition, r.wrap(params)...)
+
 	return nil
 }
 
+// addRelations selects every relation of the query's model with the model.
+func addRelations(query *orm.Query) {
+	for _, relation := range query.TableModel().Table().Relations {
+		query.Relation(relation.Field.GoName)
+	}
+}
+
+// addForUpdate locks the rows of the query's table until the end of the transaction.
+func addForUpdate(query *orm.Query, skipLocked bool) {
+	var skipLockedSQL string
+	if skipLocked {
+		skipLockedSQL = " SKIP LOCKED"
+	}
+
+	query.For(fmt.Sprintf("UPDATE OF %s%s", query.TableModel().Table().Alias, skipLockedSQL))
+}
+
+// addIDCondition matches the row whose primary key is id. The param is passed through r (see Store.FindSQL).
+func addIDCondition(query *orm.Query, id interface{}, r *paramRecorder) {
+	for _, pk := range query.TableModel().Table().PKs {
+		query.Where(fmt.Sprintf("%s.%s = ?", query.TableModel().Table().Alias, pk.SQLName), r.wrap([]interface{}{id})...)
+	}
+}
+
 // Transaction runs function fn in a transaction. If fn returns an error, the transaction is rolled back. Otherwise, the transaction is committed.
 func (s *Store) Transaction(ctx context.Context, fn func(txStore Storer) error) error {
 	if s.inTransaction() {
+		if s.savepoints {
+			return s.runInSavepoint(ctx, fn)
+		}
+
 		return errors.New("already in a transaction")
 	}
 
-	return s.db.(*pg.DB).RunInTransaction(ctx, func(tx *pg.Tx) error {
+	err := s.db.(*pg.DB).RunInTransaction(ctx, func(tx *pg.Tx) error {
 		txStore, err := NewStore(tx, s.entityModelMap)
 		if err != nil {
 			return errors.Wrap(err, "creating a new store for the transaction")
@@ -117,6 +176,62 @@ func (s *Store) Transaction(ctx context.Context, fn func(txStore Storer) error)
 
 		return fn(txStore)
 	})
+
+	// Errors of fn are returned as they are, unless they're a pg.Error, e.g., a serialization failure when committing.
+	if pgErr, ok := err.(pg.Error); ok {
+		return s.translateError(pgErr)
+	}
+
+	return err
+}
+
+// runInSavepoint runs fn in a savepoint of the store's transaction. If fn returns an error or panics, the transaction
+// is rolled back to the savepoint. Otherwise, the savepoint is released.
+func (s *Store) runInSavepoint(ctx context.Context, fn func(txStore Storer) error) (err error) {
+	tx := s.db.(*pg.Tx)
+	name := fmt.Sprintf("milo_savepoint_%d", s.savepointDepth+1)
+
+	_, err = tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return errors.Wrap(err, "creating savepoint")
+	}
+
+	txStore := s.withDB(tx)
+	txStore.savepointDepth++
+
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(r)
+		}
+	}()
+
+	err = fn(txStore)
+	if err != nil {
+		_, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if rollbackErr != nil {
+			return errors.Wrapf(err, "rolling back to savepoint failed (%s)", rollbackErr)
+		}
+
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	if err != nil {
+		return errors.Wrap(err, "releasing savepoint")
+	}
+
+	return nil
+}
+
+// withDB returns a copy of the store that uses db, for hooks and transactions.
+func (s *Store) withDB(db orm.DB) *Store {
+	return &Store{
+		db:             db,
+		entityModelMap: s.entityModelMap,
+		savepoints:     s.savepoints,
+		savepointDepth: s.savepointDepth,
+	}
 }
 
 func (s *Store) FindAll(ctx context.Context, entities interface{}) error {
@@ -147,14 +262,11 @@ func (s *Store) FindAll(ctx context.Context, entities interface{}) error {
 	query := s.db.Model(models)
 	query.Context(ctx)
 
-	relations := s.db.Model(models).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
+	addRelations(query)
 
 	err := query.Select()
 	if err != nil {
-		return errors.Wrap(err, "selecting the model")
+		return errors.Wrap(s.translateError(err), "selecting the model")
 	}
 
 	entitiesValue := reflect.ValueOf(entities).Elem()
@@ -206,14 +318,11 @@ func (s *Store) FindBy(ctx context.Context, entities interface{}, exprs ...Expre
 		return errors.Wrap(err, "applying expressions to query")
 	}
 
-	relations := s.db.Model(models).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
+	addRelations(query)
 
 	err = query.Select()
 	if err != nil {
-		return errors.Wrap(err, "selecting the model")
+		return errors.Wrap(s.translateError(err), "selecting the model")
 	}
 
 	entitiesValue := reflect.ValueOf(entities).Elem()
@@ -265,21 +374,13 @@ func (s *Store) FindByForUpdate(ctx context.Context, entities interface{}, skipL
 		return errors.Wrap(err, "applying expressions to query")
 	}
 
-	relations := s.db.Model(models).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
+	addRelations(query)
 
-	var skipLockedSQL string
-	if skipLocked {
-		skipLockedSQL = " SKIP LOCKED"
-	}
-
-	query.For(fmt.Sprintf("UPDATE OF %s%s", query.TableModel().Table().Alias, skipLockedSQL))
+	addForUpdate(query, skipLocked)
 
 	err = query.Select()
 	if err != nil {
-		return errors.Wrap(err, "selecting the model")
+		return errors.Wrap(s.translateError(err), "selecting the model")
 	}
 
 	entitiesValue := reflect.ValueOf(entities).Elem()
@@ -317,18 +418,15 @@ func (s *Store) FindOneBy(ctx context.Context, entity interface{}, exprs ...Expr
 		return errors.Wrap(err, "applying expressions to query")
 	}
 
-	relations := s.db.Model(model).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
+	addRelations(query)
 
 	err = query.First()
 	if err != nil {
 		if errors.Is(err, pg.ErrNoRows) {
-			return ErrNotFound
+			return NewNotFoundError(entity, nil, exprs, LockNone)
 		}
 
-		return errors.Wrap(err, "selecting first row")
+		return errors.Wrap(s.translateError(err), "selecting first row")
 	}
 
 	toEntity, err := model.ToEntity()
@@ -360,25 +458,17 @@ func (s *Store) FindOneByForUpdate(ctx context.Context, entity interface{}, skip
 		return errors.Wrap(err, "applying expressions to query")
 	}
 
-	relations := s.db.Model(model).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
+	addRelations(query)
 
-	var skipLockedSQL string
-	if skipLocked {
-		skipLockedSQL = " SKIP LOCKED"
-	}
-
-	query.For(fmt.Sprintf("UPDATE OF %s%s", query.TableModel().Table().Alias, skipLockedSQL))
+	addForUpdate(query, skipLocked)
 
 	err = query.First()
 	if err != nil {
 		if errors.Is(err, pg.ErrNoRows) {
-			return ErrNotFound
+			return NewNotFoundError(entity, nil, exprs, lockMode(true, skipLocked))
 		}
 
-		return errors.Wrap(err, "selecting first row")
+		return errors.Wrap(s.translateError(err), "selecting first row")
 	}
 
 	toEntity, err := model.ToEntity()
@@ -406,22 +496,17 @@ func (s *Store) FindByID(ctx context.Context, entity interface{}, id interface{}
 	query := s.db.Model(model)
 	query.Context(ctx)
 
-	for _, pk := range query.TableModel().Table().PKs {
-		query.Where(fmt.Sprintf("%s.%s = ?", query.TableModel().Table().Alias, pk.SQLName), id)
-	}
+	addIDCondition(query, id, nil)
 
-	relations := s.db.Model(model).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
+	addRelations(query)
 
 	err := query.First()
 	if err != nil {
 		if errors.Is(err, pg.ErrNoRows) {
-			return ErrNotFound
+			return NewNotFoundError(entity, id, nil, LockNone)
 		}
 
-		return errors.Wrap(err, "selecting first row")
+		return errors.Wrap(s.translateError(err), "selecting first row")
 	}
 
 	toEntity, err := model.ToEntity()
@@ -449,29 +534,19 @@ func (s *Store) FindByIDForUpdate(ctx context.Context, entity interface{}, id in
 	query := s.db.Model(model)
 	query.Context(ctx)
 
-	for _, pk := range query.TableModel().Table().PKs {
-		query.Where(fmt.Sprintf("%s.%s = ?", query.TableModel().Table().Alias, pk.SQLName), id)
-	}
+	addIDCondition(query, id, nil)
 
-	relations := s.db.Model(model).TableModel().Table().Relations
-	for _, relation := range relations {
-		query.Relation(relation.Field.GoName)
-	}
-
-	var skipLockedSQL string
-	if skipLocked {
-		skipLockedSQL = " SKIP LOCKED"
-	}
+	addRelations(query)
 
-	query.For(fmt.Sprintf("UPDATE OF %s%s", query.TableModel().Table().Alias, skipLockedSQL))
+	addForUpdate(query, skipLocked)
 
 	err := query.First()
 	if err != nil {
 		if errors.Is(err, pg.ErrNoRows) {
-			return ErrNotFound
+			return NewNotFoundError(entity, id, nil, lockMode(true, skipLocked))
 		}
 
-		return errors.Wrap(err, "selecting first row")
+		return errors.Wrap(s.translateError(err), "selecting first row")
 	}
 
 	toEntity, err := model.ToEntity()
@@ -501,6 +576,23 @@ func (s *Store) Save(ctx context.Context, entity interface{}) error {
 		return errors.Wrapf(err, "converting entity to model")
 	}
 
+	err = Validate(ctx, entity, model)
+	if err != nil {
+		return err
+	}
+
+	// Like on a store of a database, a failed save outside of Transaction doesn't abort the transaction.
+	if s.savepoints && s.savepointDepth == 0 {
+		return s.runInSavepoint(ctx, func(txStore Storer) error {
+			return txStore.(*Store).save(ctx, entity, model)
+		})
+	}
+
+	return s.save(ctx, entity, model)
+}
+
+func (s *Store) save(ctx context.Context, entity interface{}, model Model) error {
+	var err error
 	var tx *pg.Tx
 
 	if s.inTransaction() {
@@ -515,10 +607,7 @@ func (s *Store) Save(ctx context.Context, entity interface{}) error {
 	}
 
 	if model, ok := model.(Hook); ok {
-		store, err := NewStore(tx, s.entityModelMap)
-		if err != nil {
-			return errors.Wrap(err, "creating new store for before save hook")
-		}
+		store := s.withDB(tx)
 
 		err = model.BeforeSave(ctx, store, entity)
 		if err != nil {
@@ -528,7 +617,7 @@ func (s *Store) Save(ctx context.Context, entity interface{}) error {
 
 	exists, err := tx.Model(model).WherePK().Exists()
 	if err != nil {
-		return errors.Wrap(err, "exists")
+		return errors.Wrap(s.translateError(err), "exists")
 	}
 
 	// Insert
@@ -536,18 +625,18 @@ func (s *Store) Save(ctx context.Context, entity interface{}) error {
 	if !exists {
 		_, err := tx.Model(model).Context(ctx).Insert()
 		if err != nil {
-			return errors.Wrap(err, "inserting model")
+			return errors.Wrap(s.translateError(err), "inserting model")
 		}
 
 		err = insertRelated(ctx, tx, model)
 		if err != nil {
-			return errors.Wrap(err, "inserting related models (insert)")
+			return errors.Wrap(s.translateError(err), "inserting related models (insert)")
 		}
 
 		if !s.inTransaction() {
 			err = tx.Commit()
 			if err != nil {
-				return errors.Wrap(err, "committing transaction")
+				return errors.Wrap(s.translateError(err), "committing transaction")
 			}
 		}
 
@@ -558,23 +647,23 @@ func (s *Store) Save(ctx context.Context, entity interface{}) error {
 
 	_, err = tx.Model(model).Context(ctx).WherePK().Update()
 	if err != nil {
-		return errors.Wrap(err, "updating model")
+		return errors.Wrap(s.translateError(err), "updating model")
 	}
 
 	err = deleteRelated(ctx, tx, model)
 	if err != nil {
-		return errors.Wrap(err, "deleting related models (update)")
+		return errors.Wrap(s.translateError(err), "deleting related models (update)")
 	}
 
 	err = insertRelated(ctx, tx, model)
 	if err != nil {
-		return errors.Wrap(err, "inserting related models (update)")
+		return errors.Wrap(s.translateError(err), "inserting related models (update)")
 	}
 
 	if !s.inTransaction() {
 		err = tx.Commit()
 		if err != nil {
-			return errors.Wrap(err, "committing transaction")
+			return errors.Wrap(s.translateError(err), "committing transaction")
 		}
 	}
 
@@ -597,6 +686,17 @@ func (s *Store) Delete(ctx context.Context, entity interface{}) error {
 		return errors.Wrapf(err, "converting entity to model")
 	}
 
+	if s.savepoints && s.savepointDepth == 0 {
+		return s.runInSavepoint(ctx, func(txStore Storer) error {
+			return txStore.(*Store).delete(ctx, entity, model)
+		})
+	}
+
+	return s.delete(ctx, entity, model)
+}
+
+func (s *Store) delete(ctx context.Context, entity interface{}, model Model) error {
+	var err error
 	var tx *pg.Tx
 
 	if s.inTransaction() {
@@ -611,10 +711,7 @@ func (s *Store) Delete(ctx context.Context, entity interface{}) error {
 	}
 
 	if model, ok := model.(Hook); ok {
-		store, err := NewStore(tx, s.entityModelMap)
-		if err != nil {
-			return errors.Wrap(err, "creating new store for before delete hook")
-		}
+		store := s.withDB(tx)
 
 		err = model.BeforeDelete(ctx, store, entity)
 		if err != nil {
@@ -624,24 +721,56 @@ func (s *Store) Delete(ctx context.Context, entity interface{}) error {
 
 	_, err = tx.Model(model).Context(ctx).WherePK().Delete()
 	if err != nil {
-		return err
+		return errors.Wrap(s.translateError(err), "deleting model")
 	}
 
 	err = deleteRelated(ctx, tx, model)
 	if err != nil {
-		return errors.Wrap(err, "deleting related models (delete)")
+		return errors.Wrap(s.translateError(err), "deleting related models (delete)")
 	}
 
 	if !s.inTransaction() {
 		err = tx.Commit()
 		if err != nil {
-			return errors.Wrap(err, "committing transaction")
+			return errors.Wrap(s.translateError(err), "committing transaction")
 		}
 	}
 
 	return nil
 }
 
+// translateError returns a *DBError for a pg.Error of one of the kinds of DBError, or err otherwise.
+func (s *Store) translateError(err error) error {
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	kind, ok := dbErrorKinds[pgErr.Field('C')]
+	if !ok {
+		return err
+	}
+
+	dbErr = &DBError{
+		Kind:       kind,
+		Constraint: pgErr.Field('n'),
+		Table:      pgErr.Field('t'),
+		Column:     pgErr.Field('c'),
+		err:        pgErr,
+	}
+
+	if kind == ErrUniqueViolation {
+		dbErr.Index = declaredIndex(s.entityModelMap, dbErr.Constraint)
+	}
+
+	return dbErr
+}
+
 func insertRelated(ctx context.Context, db orm.DB, model Model) error {
 	modelValue := reflect.ValueOf(model)
 
@@ -0,0 +1,139 @@
+package milo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/go-pg/pg/v10/types"
+	"github.com/pkg/errors"
+)
+
+// FindQuery describes a finder call for Store.FindSQL.
+type FindQuery struct {
+	// Entity is the entity pointer (as passed to FindOneBy and FindByID) or the pointer to a slice of entity pointers
+	// (as passed to FindAll and FindBy).
+	Entity interface{}
+	// ID finds the entity by primary key, like FindByID. ID can't be used with Exprs or a slice Entity.
+	ID interface{}
+	// Exprs are the expressions passed to the finder.
+	Exprs []Expression
+	// ForUpdate and SkipLocked are the locking of the ForUpdate finders.
+	ForUpdate  bool
+	SkipLocked bool
+}
+
+// FindSQL returns the SQL of the query a finder would run for q, without running it. Values are replaced with
+// numbered placeholders ($1, $2, ...) and returned as params. The query includes the joins of has-one and
+// belongs-to relations and the FOR UPDATE clause; go-pg selects has-many relations with a separate query per relation
+// after the rows are found, so those queries aren't included.
+func (s *Store) FindSQL(q FindQuery) (string, []interface{}, error) {
+	entityType := reflect.TypeOf(q.Entity)
+	if entityType == nil || entityType.Kind() != reflect.Ptr {
+		return "", nil, errors.New("entity must be a pointer")
+	}
+
+	many := entityType.Elem().Kind() == reflect.Slice
+	if many {
+		entityType = entityType.Elem().Elem()
+	}
+
+	if q.ID != nil && (many || len(q.Exprs) > 0) {
+		return "", nil, errors.New("id can only be used to find a single entity without expressions")
+	}
+
+	modelType, ok := s.entityModelMap[entityType]
+	if !ok {
+		return "", nil, fmt.Errorf("unable to find model type for entity type %s", entityType.String())
+	}
+
+	var model interface{}
+	if many {
+		model = reflect.New(reflect.SliceOf(modelType)).Interface()
+	} else {
+		model = reflect.New(modelType.Elem()).Interface()
+	}
+
+	r := &paramRecorder{}
+
+	query := s.db.Model(model)
+
+	if q.ID != nil {
+		addIDCondition(query, q.ID, r)
+	} else {
+		err := applyExpressionsToQueryRecording(q.Exprs, query, r)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "applying expressions to query")
+		}
+	}
+
+	addRelations(query)
+
+	if q.ForUpdate {
+		addForUpdate(query, q.SkipLocked)
+	}
+
+	if !many {
+		// Like query.First.
+		table := query.TableModel().Table()
+
+		pks := make([]string, len(table.PKs))
+		for i, pk := range table.PKs {
+			pks[i] = string(table.Alias) + "." + string(pk.Column)
+		}
+
+		query.OrderExpr(strings.Join(pks, ", ")).Limit(1)
+	}
+
+	b, err := orm.NewSelectQuery(query).AppendQuery(s.db.Formatter(), nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "formatting query")
+	}
+
+	return string(b), r.params, nil
+}
+
+// paramRecorder replaces params with numbered placeholders when a query is formatted and records their values in
+// the order they appear in the SQL. A nil recorder leaves params unchanged.
+type paramRecorder struct {
+	params []interface{}
+}
+
+func (r *paramRecorder) wrap(params []interface{}) []interface{} {
+	if r == nil {
+		return params
+	}
+
+	wrapped := make([]interface{}, len(params))
+	for i, param := range params {
+		wrapped[i] = recordedParam{
+			r:     r,
+			value: param,
+		}
+	}
+
+	return wrapped
+}
+
+type recordedParam struct {
+	r     *paramRecorder
+	value interface{}
+}
+
+var _ types.ValueAppender = recordedParam{}
+
+func (p recordedParam) AppendValue(b []byte, flags int) ([]byte, error) {
+	value := p.value
+	if array, ok := value.(*types.Array); ok {
+		value = array.Value()
+	}
+
+	p.r.params = append(p.r.params, value)
+
+	b = append(b, '$')
+	b = strconv.AppendInt(b, int64(len(p.r.params)), 10)
+
+	return b, nil
+}
@@ -0,0 +1,134 @@
+package milo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSQLStore(t *testing.T) *Store {
+	// FindSQL never runs a query, so the database doesn't have to be reachable.
+	db := pg.Connect(&pg.Options{
+		Addr: "localhost:8200",
+	})
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	store, err := NewStore(db, EntityModelMap{
+		reflect.TypeOf(&userEntity{}):      reflect.TypeOf(&userModel{}),
+		reflect.TypeOf(&expressionModel{}): reflect.TypeOf(&expressionModel{}),
+	})
+	assert.NoError(t, err)
+
+	return store
+}
+
+func TestStore_FindSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	tests := []struct {
+		name           string
+		query          FindQuery
+		expectedSQL    string
+		expectedParams []interface{}
+	}{
+		{
+			name:  "find all",
+			query: FindQuery{Entity: &[]*userEntity{}},
+			expectedSQL: `SELECT "user_model"."id", "user_model"."name_first", "user_model"."name_last", "user_model"."profile_id", ` +
+				`"profile"."id" AS "profile__id", "profile"."about" AS "profile__about", "profile"."favorite_color" AS "profile__favorite_color", ` +
+				`"location"."id" AS "location__id", "location"."user_id" AS "location__user_id", "location"."latitude" AS "location__latitude", "location"."longitude" AS "location__longitude" ` +
+				`FROM "users" AS "user_model" ` +
+				`LEFT JOIN "profiles" AS "profile" ON "profile"."id" = "user_model"."profile_id" ` +
+				`LEFT JOIN "locations" AS "location" ON "location"."user_id" = "user_model"."id"`,
+			expectedParams: nil,
+		},
+		{
+			name: "find by",
+			query: FindQuery{
+				Entity: &[]*userEntity{},
+				Exprs:  []Expression{Equal("name_first", "Jane"), Or(Equal("name_last", "Doe"), IsNull("name_last")), OrderByDesc(Lower("name_last"))},
+			},
+			expectedSQL:    `WHERE (("user_model"."name_first" = $1 AND ("user_model"."name_last" = $2 OR "user_model"."name_last" IS NULL))) ORDER BY lower("user_model"."name_last") DESC`,
+			expectedParams: []interface{}{"Jane", "Doe"},
+		},
+		{
+			name: "find by for update",
+			query: FindQuery{
+				Entity:     &[]*userEntity{},
+				Exprs:      []Expression{Equal("name_first", "Jane")},
+				ForUpdate:  true,
+				SkipLocked: true,
+			},
+			expectedSQL:    `WHERE (("user_model"."name_first" = $1)) FOR UPDATE OF "user_model" SKIP LOCKED`,
+			expectedParams: []interface{}{"Jane"},
+		},
+		{
+			name: "find one by",
+			query: FindQuery{
+				Entity: &userEntity{},
+				Exprs:  []Expression{Gt("name_first", "J")},
+			},
+			expectedSQL:    `WHERE (("user_model"."name_first" > $1)) ORDER BY "user_model"."id" LIMIT 1`,
+			expectedParams: []interface{}{"J"},
+		},
+		{
+			name: "find by id for update",
+			query: FindQuery{
+				Entity:    &userEntity{},
+				ID:        "123",
+				ForUpdate: true,
+			},
+			expectedSQL:    `WHERE ("user_model".id = $1) ORDER BY "user_model"."id" LIMIT 1 FOR UPDATE OF "user_model"`,
+			expectedParams: []interface{}{"123"},
+		},
+		{
+			name: "params in order of the sql",
+			query: FindQuery{
+				Entity: &[]*expressionModel{},
+				Exprs: []Expression{
+					OrderByRank(Matches([]interface{}{"name_first"}, "jane", "simple")),
+					ArrayContains("tags", []string{"a"}),
+					Raw("length(?TableAlias.name_last) > ?", 3),
+				},
+			},
+			expectedSQL:    `WHERE (("expression_model"."tags" @> $1 AND (length("expression_model".name_last) > $2))) ORDER BY ts_rank(to_tsvector($3, coalesce("expression_model"."name_first"::text, '')), websearch_to_tsquery($4, $5)) DESC`,
+			expectedParams: []interface{}{[]string{"a"}, 3, "simple", "simple", "jane"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sql, params, err := store.FindSQL(tt.query)
+			assert.NoError(err)
+			assert.Contains(sql, tt.expectedSQL)
+			assert.Equal(tt.expectedParams, params)
+		})
+	}
+}
+
+func TestStore_FindSQLErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	store := newTestSQLStore(t)
+
+	_, _, err := store.FindSQL(FindQuery{Entity: userEntity{}})
+	assert.Error(err)
+
+	_, _, err = store.FindSQL(FindQuery{Entity: &profileEntity{}})
+	assert.Error(err)
+
+	_, _, err = store.FindSQL(FindQuery{Entity: &[]*userEntity{}, ID: "123"})
+	assert.Error(err)
+
+	_, _, err = store.FindSQL(FindQuery{Entity: &userEntity{}, Exprs: []Expression{Equal("nme_first", "Jane")}})
+
+	var unknownErr *UnknownColumnError
+	assert.ErrorAs(err, &unknownErr)
+}
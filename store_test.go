@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/go-pg/pg/v10"
-	"github.com/go-pg/pg/v10/orm"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -581,6 +580,37 @@ func TestStore_Expressions(t *testing.T) {
 	assert.Contains(foundUsers, user)
 	assert.Contains(foundUsers, user3)
 
+	// FindBy (function).
+	foundUsers = []*userEntityPtr{}
+	err = store.FindBy(context.Background(), &foundUsers, Equal(Lower("name_first"), "john"), OrderBy(Upper("name_last")))
+	assert.NoError(err)
+	assert.Len(foundUsers, 1)
+	assert.Contains(foundUsers, user)
+
+	// FindBy (raw).
+	foundUsers = []*userEntityPtr{}
+	err = store.FindBy(context.Background(), &foundUsers, Or(Raw("length(?TableAlias.name_first) = ?", 5), Equal("name_last", user.NameLast)))
+	assert.NoError(err)
+	assert.Len(foundUsers, 2)
+	assert.Contains(foundUsers, user)
+	assert.Contains(foundUsers, user3)
+
+	// FindBy (full-text search, ranked).
+	match := Matches([]interface{}{"name_first", "name_last"}, "smith or john", "simple")
+	foundUsers = []*userEntityPtr{}
+	err = store.FindBy(context.Background(), &foundUsers, match, OrderByRank(match))
+	assert.NoError(err)
+	assert.Len(foundUsers, 2)
+	assert.Equal(user, foundUsers[0])
+	assert.Contains(foundUsers, user3)
+
+	// FindBy (unknown column).
+	foundUsers = []*userEntityPtr{}
+	err = store.FindBy(context.Background(), &foundUsers, Equal("name_frist", user.NameFirst))
+	var unknownColumnErr *UnknownColumnError
+	assert.ErrorAs(err, &unknownColumnErr)
+	assert.Equal([]string{"name_first", "name_last"}, unknownColumnErr.Suggestions)
+
 	// FindBy (one column, no match).
 	foundUsers = []*userEntityPtr{}
 	err = store.FindBy(context.Background(), &foundUsers, Equal("name_first", "foo"))
@@ -612,6 +642,13 @@ func TestStore_Expressions(t *testing.T) {
 	err = store.FindOneByForUpdate(context.Background(), foundUser, true, Equal("name_first", "foo"))
 	assert.Error(err)
 	assert.ErrorIs(err, ErrNotFound)
+
+	var notFoundErr *NotFoundError
+	if assert.ErrorAs(err, &notFoundErr) {
+		assert.Equal(reflect.TypeOf(foundUser), notFoundErr.EntityType)
+		assert.Equal([]Expression{Equal("name_first", "foo")}, notFoundErr.Expressions)
+		assert.Equal(LockForUpdateSkipLocked, notFoundErr.Lock)
+	}
 	assert.NotEqual(user, foundUser)
 
 	// FindByID.
@@ -625,6 +662,7 @@ func TestStore_Expressions(t *testing.T) {
 	err = store.FindByID(context.Background(), foundUser, "foo")
 	assert.Error(err)
 	assert.ErrorIs(err, ErrNotFound)
+	assert.EqualError(err, "entity not found: *milo.userEntityPtr with ID foo")
 
 	// FindByIDForUpdate (don't skip locked, no match).
 	foundUser = &userEntityPtr{}
@@ -840,27 +878,8 @@ func TestStore_Hooks(t *testing.T) {
 }
 
 func createSchema(db *pg.DB) error {
-	models := []interface{}{
-		(*userModelPtr)(nil),
-		(*profileModel)(nil),
-		(*locationModel)(nil),
-		(*addressModel)(nil),
-		(*hookModel)(nil),
-	}
-
-	for _, model := range models {
-		err := db.Model(model).DropTable(&orm.DropTableOptions{
-			IfExists: true,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = db.Model(model).CreateTable(&orm.CreateTableOptions{})
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return CreateSchema(context.Background(), db, EntityModelMap{
+		reflect.TypeOf(&userEntityPtr{}): reflect.TypeOf(&userModelPtr{}),
+		reflect.TypeOf(&hookEntity{}):    reflect.TypeOf(&hookModel{}),
+	}, &CreateSchemaOptions{DropFirst: true})
 }
@@ -0,0 +1,128 @@
+package milo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrValidation = errors.New("validation failed")
+
+// Validator is implemented by entities and models that validate themselves. Save calls Validate on the entity, then
+// on the model converted from it, before the before save hook and any write. Return ValidationErrors to report
+// several invalid fields at once.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// FieldError is an invalid field. Field is the path of the field, e.g., addresses[0].city.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors are the invalid fields of an entity or model. errors.Is(err, milo.ErrValidation) is true for it, and
+// errors.As gives the fields:
+//
+//	var validationErrs milo.ValidationErrors
+//	if errors.As(err, &validationErrs) {
+//		for _, fieldErr := range validationErrs {
+type ValidationErrors []*FieldError
+
+// Add adds an invalid field.
+func (e *ValidationErrors) Add(field string, message string) {
+	*e = append(*e, &FieldError{
+		Field:   field,
+		Message: message,
+	})
+}
+
+// Merge adds the invalid fields of err, the error of validating the value of field, e.g., a related entity, with
+// their paths prefixed by field. Any other error is added as an invalid field. Merge does nothing if err is nil.
+//
+//	for i, address := range c.Addresses {
+//		errs.Merge(fmt.Sprintf("addresses[%d]", i), address.Validate(ctx))
+//	}
+func (e *ValidationErrors) Merge(field string, err error) {
+	if err == nil {
+		return
+	}
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		e.Add(field, err.Error())
+		return
+	}
+
+	for _, fieldErr := range validationErrs {
+		e.Add(joinFieldPath(field, fieldErr.Field), fieldErr.Message)
+	}
+}
+
+// Err returns e, or nil if there are no invalid fields, so Validate can end with return errs.Err().
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fieldErr := range e {
+		msgs[i] = fieldErr.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(msgs, "; "))
+}
+
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func joinFieldPath(prefix string, field string) string {
+	if prefix == "" {
+		return field
+	}
+
+	if field == "" {
+		return prefix
+	}
+
+	if strings.HasPrefix(field, "[") {
+		return prefix + field
+	}
+
+	return prefix + "." + field
+}
+
+// Validate calls Validate on entity and then on model, if they implement Validator. Storer implementations call it in
+// Save before writing.
+func Validate(ctx context.Context, entity interface{}, model Model) error {
+	if entity, ok := entity.(Validator); ok {
+		err := entity.Validate(ctx)
+		if err != nil {
+			return errors.Wrap(err, "validating entity")
+		}
+	}
+
+	if model, ok := model.(Validator); ok {
+		err := model.Validate(ctx)
+		if err != nil {
+			return errors.Wrap(err, "validating model")
+		}
+	}
+
+	return nil
+}
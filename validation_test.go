@@ -0,0 +1,163 @@
+package milo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderEntity struct {
+	ID    string
+	Lines []*orderLineEntity
+}
+
+type orderLineEntity struct {
+	SKU      string
+	Quantity int
+}
+
+func (o *orderEntity) Validate(ctx context.Context) error {
+	var errs ValidationErrors
+
+	if len(o.Lines) == 0 {
+		errs.Add("lines", "must not be empty")
+	}
+
+	for i, line := range o.Lines {
+		errs.Merge(fmt.Sprintf("lines[%d]", i), line.Validate(ctx))
+	}
+
+	return errs.Err()
+}
+
+func (l *orderLineEntity) Validate(ctx context.Context) error {
+	var errs ValidationErrors
+
+	if l.SKU == "" {
+		errs.Add("sku", "is required")
+	}
+
+	if l.Quantity <= 0 {
+		errs.Add("quantity", "must be positive")
+	}
+
+	return errs.Err()
+}
+
+type orderModel struct {
+	tableName struct{} `pg:"orders"`
+
+	ID    string `pg:"id"`
+	Lines int    `pg:"lines,use_zero"`
+}
+
+var _ Validator = (*orderEntity)(nil)
+var _ Validator = (*orderModel)(nil)
+
+func (o *orderModel) FromEntity(e interface{}) error {
+	entity := e.(*orderEntity)
+
+	o.ID = entity.ID
+	o.Lines = len(entity.Lines)
+
+	return nil
+}
+
+func (o *orderModel) ToEntity() (interface{}, error) {
+	return &orderEntity{ID: o.ID}, nil
+}
+
+func (o *orderModel) Validate(ctx context.Context) error {
+	if o.ID == "" {
+		return errors.New("id is required")
+	}
+
+	return nil
+}
+
+func TestValidationErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	var errs ValidationErrors
+	assert.NoError(errs.Err())
+
+	errs.Add("name", "is required")
+	errs.Merge("addresses[0]", ValidationErrors{{Field: "city", Message: "is required"}})
+	errs.Merge("addresses", ValidationErrors{{Field: "[1]", Message: "is a duplicate"}})
+	errs.Merge("email", errors.New("is invalid"))
+	errs.Merge("phone", nil)
+
+	err := errors.Wrap(errs.Err(), "validating entity")
+
+	assert.EqualError(err, "validating entity: validation failed: name: is required; addresses[0].city: is required; addresses[1]: is a duplicate; email: is invalid")
+	assert.ErrorIs(err, ErrValidation)
+
+	var validationErrs ValidationErrors
+	if assert.ErrorAs(err, &validationErrs) {
+		assert.Equal(ValidationErrors{
+			{Field: "name", Message: "is required"},
+			{Field: "addresses[0].city", Message: "is required"},
+			{Field: "addresses[1]", Message: "is a duplicate"},
+			{Field: "email", Message: "is invalid"},
+		}, validationErrs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		entity      *orderEntity
+		expectedErr string
+	}{
+		{
+			name:   "valid",
+			entity: &orderEntity{ID: "1", Lines: []*orderLineEntity{{SKU: "a", Quantity: 1}}},
+		},
+		{
+			name:        "invalid entity",
+			entity:      &orderEntity{ID: "1", Lines: []*orderLineEntity{{SKU: "a", Quantity: 1}, {Quantity: 0}}},
+			expectedErr: "validating entity: validation failed: lines[1].sku: is required; lines[1].quantity: must be positive",
+		},
+		{
+			name:        "invalid model",
+			entity:      &orderEntity{Lines: []*orderLineEntity{{SKU: "a", Quantity: 1}}},
+			expectedErr: "validating model: id is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			model := &orderModel{}
+			assert.NoError(model.FromEntity(tt.entity))
+
+			err := Validate(context.Background(), tt.entity, model)
+			if tt.expectedErr == "" {
+				assert.NoError(err)
+			} else {
+				assert.EqualError(err, tt.expectedErr)
+			}
+		})
+	}
+
+	// Save validates before it connects to the database.
+	db := pg.Connect(&pg.Options{
+		Addr: "localhost:1",
+	})
+	defer db.Close()
+
+	store, err := NewStore(db, EntityModelMap{
+		reflect.TypeOf(&orderEntity{}): reflect.TypeOf(&orderModel{}),
+	})
+	assert.NoError(t, err)
+
+	err = store.Save(context.Background(), &orderEntity{ID: "1"})
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.EqualError(t, err, "validating entity: validation failed: lines: must not be empty")
+}